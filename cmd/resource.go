@@ -118,6 +118,18 @@ func manageResource(operation string, cmd *cobra.Command, config *config.Config)
 			"ing",
 			&config.Resource.Ingress,
 		},
+		{
+			"ev",
+			&config.Resource.Event,
+		},
+		{
+			"no",
+			&config.Resource.Node,
+		},
+		{
+			"sts",
+			&config.Resource.StatefulSet,
+		},
 	}
 
 	for _, flag := range flags {
@@ -165,5 +177,8 @@ func init() {
 	resourceCmd.PersistentFlags().Bool("secret", false, "watch for Secrets")
 	resourceCmd.PersistentFlags().Bool("cm", false, "watch for ConfigMaps")
 	resourceCmd.PersistentFlags().Bool("ing", false, "watch for Ingresses")
+	resourceCmd.PersistentFlags().Bool("ev", false, "watch for Events")
+	resourceCmd.PersistentFlags().Bool("no", false, "watch for Nodes")
+	resourceCmd.PersistentFlags().Bool("sts", false, "watch for StatefulSets")
 
 }
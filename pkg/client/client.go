@@ -1,24 +1,65 @@
 package client
 
 import (
-	"fmt"
-
 	"github.com/Sirupsen/logrus"
 	"github.com/walk1ng/k8swatch/pkg/config"
 	"github.com/walk1ng/k8swatch/pkg/controller"
 	"github.com/walk1ng/k8swatch/pkg/handlers"
 )
 
-// Run runs the event processing with the given handler
+// Run runs the event processing with the enabled handlers
 func Run(c *config.Config) {
-	var eventHandler handlers.Handler
-	// currently only support the Default handler
+	var sinks = []struct {
+		name    string
+		enabled bool
+		handler handlers.EventHandler
+		rules   []config.Rule
+	}{
+		{
+			"slack",
+			c.Handler.Slack.WebhookURL != "",
+			&handlers.Slack{},
+			c.Handler.Slack.Rules,
+		},
+		{
+			"msteams",
+			c.Handler.MSTeams.WebhookURL != "",
+			&handlers.MSTeams{},
+			c.Handler.MSTeams.Rules,
+		},
+		{
+			"mattermost",
+			c.Handler.Mattermost.WebhookURL != "",
+			&handlers.Mattermost{},
+			c.Handler.Mattermost.Rules,
+		},
+		{
+			"webhook",
+			c.Handler.Webhook.URL != "",
+			&handlers.Webhook{},
+			c.Handler.Webhook.Rules,
+		},
+		{
+			"default",
+			c.Handler.Default.Format != "" || c.Handler.Default.Output != "" || len(c.Handler.Default.Rules) != 0,
+			&handlers.Default{},
+			c.Handler.Default.Rules,
+		},
+	}
+
+	eventHandler := &handlers.Composite{}
+	for _, sink := range sinks {
+		if !sink.enabled {
+			continue
+		}
+		if err := eventHandler.Add(sink.name, sink.handler, sink.rules); err != nil {
+			logrus.Fatal(err)
+		}
+	}
 
-	switch c.Handler {
-	case config.Handler{}:
-		eventHandler = &handlers.Default{}
-	default:
-		fmt.Println("non-match handler")
+	// print events when no handler is configured
+	if eventHandler.Len() == 0 {
+		eventHandler.Add("default", &handlers.Default{}, nil)
 	}
 
 	if err := eventHandler.Init(c); err != nil {
@@ -1,11 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
+	"github.com/walk1ng/k8swatch/pkg/filter"
 	"gopkg.in/yaml.v2"
 )
 
@@ -25,17 +28,240 @@ type Resource struct {
 	Secret                bool `json:"secret"`
 	ConfigMap             bool `json:"configmap"`
 	Ingress               bool `json:"ing"`
+	Event                 bool `json:"ev"`
+	Node                  bool `json:"no"`
+	StatefulSet           bool `json:"sts"`
 }
 
-// Handler struct:
+// Rule struct: routing rule of a handler
+// an event matches when every non-empty condition matches
+type Rule struct {
+	// Kinds of the object, e.g. Pod or Deployment
+	Kinds []string `json:"kinds"`
+	// Namespaces are glob patterns, e.g. prod-*
+	Namespaces []string `json:"namespaces"`
+	// EventTypes are create, update, delete or notification
+	EventTypes []string `json:"eventtypes"`
+	// Labels is a label selector, e.g. app=web,tier!=cache
+	Labels string `json:"labels"`
+	// Owners are glob patterns of the top level controller as Kind/name,
+	// e.g. Deployment/web-*, they need owner enrichment
+	Owners []string `json:"owners"`
+}
+
+// Default struct: default handler configuration
+type Default struct {
+	// Format is one of json, logfmt or table, json if empty
+	Format string `json:"format"`
+	// Output is stdout, stderr or a file path, stdout if empty
+	Output string `json:"output"`
+	Rules  []Rule `json:"rules"`
+}
+
+// Slack struct: slack handler configuration
+type Slack struct {
+	// WebhookURL is the Slack incoming webhook url
+	WebhookURL string `json:"webhookurl"`
+	// Channel overrides the default channel of the webhook
+	Channel string `json:"channel"`
+	// Templates are text/template message templates by event type,
+	// i.e. create, update, delete or notification
+	Templates map[string]string `json:"templates"`
+	Rules     []Rule            `json:"rules"`
+}
+
+// MSTeams struct: microsoft teams handler configuration
+type MSTeams struct {
+	// WebhookURL is the Teams incoming webhook url
+	WebhookURL string            `json:"webhookurl"`
+	Templates  map[string]string `json:"templates"`
+	Rules      []Rule            `json:"rules"`
+}
+
+// Mattermost struct: mattermost handler configuration
+type Mattermost struct {
+	// WebhookURL is the Mattermost incoming webhook url
+	WebhookURL string `json:"webhookurl"`
+	// Channel, Username and IconURL override the webhook defaults
+	Channel   string            `json:"channel"`
+	Username  string            `json:"username"`
+	IconURL   string            `json:"iconurl"`
+	Templates map[string]string `json:"templates"`
+	Rules     []Rule            `json:"rules"`
+}
+
+// Webhook struct: webhook handler configuration
+type Webhook struct {
+	// URL receives a POST for every event
+	URL string `json:"url"`
+	// Headers are added to every request
+	Headers map[string]string `json:"headers"`
+	// BearerToken takes precedence over basic auth
+	BearerToken string `json:"bearertoken"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	// Secret signs the body in the X-K8swatch-Signature header
+	Secret string `json:"secret"`
+	// CAFile verifies the server certificate
+	CAFile             string `json:"cafile"`
+	InsecureSkipVerify bool   `json:"insecureskipverify"`
+	// Templates render the text field of the envelope
+	Templates map[string]string `json:"templates"`
+	Rules     []Rule            `json:"rules"`
+}
+
+// Handler struct: handlers configuration
 type Handler struct {
+	Default    Default    `json:"default"`
+	Slack      Slack      `json:"slack"`
+	MSTeams    MSTeams    `json:"msteams"`
+	Mattermost Mattermost `json:"mattermost"`
+	Webhook    Webhook    `json:"webhook"`
+}
+
+// Diff struct: update diff configuration
+type Diff struct {
+	// IgnorePaths are dotted field paths left out of update diffs, updates changing
+	// nothing else do not fire, when empty metadata.resourceVersion,
+	// metadata.managedFields, metadata.generation and status
+	IgnorePaths []string `json:"ignorepaths"`
+}
+
+// Update struct: rule deciding which updates of a kind fire an event
+// an update fires when every rule of its kind lets it through
+type Update struct {
+	// Kind the rule applies to, e.g. Deployment, every kind if empty
+	Kind string `json:"kind"`
+	// SkipSameGeneration drops updates leaving metadata.generation unchanged,
+	// kinds without a generation such as Pod never fire with it
+	SkipSameGeneration bool `json:"skipsamegeneration"`
+	// IgnorePaths are dotted field paths whose changes alone do not fire,
+	// e.g. status or metadata.annotations, metadata.managedFields is always ignored
+	IgnorePaths []string `json:"ignorepaths"`
+	// Paths are JSONPaths, only changes to them fire, e.g. .spec.replicas
+	Paths []string `json:"paths"`
+}
+
+// Selector struct: server side filtering of a resource
+type Selector struct {
+	// LabelSelector e.g. app=web,tier!=cache
+	LabelSelector string `json:"labelselector"`
+	// FieldSelector e.g. status.phase=Running
+	FieldSelector string `json:"fieldselector"`
+}
+
+// Namespaces struct: namespace allowlist and denylist
+// entries are glob patterns, e.g. kube-*
+type Namespaces struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+// Filter struct: CEL expression over object, oldObject, eventType and kind
+// e.g. kind == "Pod" && object.status.containerStatuses.exists(c, c.restartCount > 5)
+type Filter struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+// Analyzers struct: high level notifications derived from watched resources
+type Analyzers struct {
+	// PodHealth reports OOMKilled, CrashLoopBackOff, ImagePullBackOff and evicted pods
+	PodHealth bool `json:"podhealth"`
+	// Rollout follows the rollouts of Deployments, DaemonSets and StatefulSets
+	Rollout bool `json:"rollout"`
+}
+
+// Events struct: filtering of watched core/v1 Events
+// an event is reported when every non-empty condition matches
+type Events struct {
+	// Types e.g. Warning or Normal, Warning if empty
+	Types []string `json:"types"`
+	// Reasons e.g. FailedScheduling or BackOff
+	Reasons []string `json:"reasons"`
+	// InvolvedKinds are the kinds of the involved objects, e.g. Pod or Node
+	InvolvedKinds []string `json:"involvedkinds"`
+}
+
+// Enrichment struct: metadata attached to every event
+type Enrichment struct {
+	// Owners resolves the top level controller of objects, e.g. the Deployment of a pod,
+	// or of the objects involved in core/v1 Events, which caches pods as well
+	Owners bool `json:"owners"`
+}
+
+// LeaderElection struct: Lease based leader election between replicas,
+// only the leader watches resources
+type LeaderElection struct {
+	Enabled bool `json:"enabled"`
+	// LeaseName is k8swatch if empty
+	LeaseName string `json:"leasename"`
+	// LeaseNamespace is the namespace of the pod if empty
+	LeaseNamespace string `json:"leasenamespace"`
+	// LeaseDuration, RenewDeadline and RetryPeriod are durations
+	// such as 15s, they default to 15s, 10s and 2s
+	LeaseDuration string `json:"leaseduration"`
+	RenewDeadline string `json:"renewdeadline"`
+	RetryPeriod   string `json:"retryperiod"`
+}
+
+// Server struct: health and metrics endpoint
+type Server struct {
+	// Address serves /healthz, /readyz and /metrics, e.g. :8080, disabled if empty
+	Address string `json:"address"`
+}
+
+// Queue struct: processing of the events of a resource, zero values keep the defaults
+type Queue struct {
+	// Workers process events concurrently, the events of one object
+	// are still processed in order by a single worker, 1 by default
+	Workers int `json:"workers"`
+	// QPS and Burst limit the overall rate of retries, 10 and 100 by default
+	QPS   float64 `json:"qps"`
+	Burst int     `json:"burst"`
+	// BaseDelay and MaxDelay bound the exponential backoff between the
+	// retries of an event, they are durations such as 500ms, 500ms and 30s by default
+	BaseDelay string `json:"basedelay"`
+	MaxDelay  string `json:"maxdelay"`
+	// MaxRetries is 5 by default, a negative value disables retries
+	MaxRetries int `json:"maxretries"`
+}
+
+// Shutdown struct: delivery of the queued events on SIGINT or SIGTERM
+type Shutdown struct {
+	// DrainTimeout bounds the delivery of the queued events, then the flush
+	// of the handlers, e.g. 10s, it defaults to 10s
+	DrainTimeout string `json:"draintimeout"`
 }
 
 // Config struct: k8swatch configuration
 type Config struct {
-	Handler   Handler  `json:"handler"`
-	Resource  Resource `json:"resource"`
+	// Cluster names the watched cluster in messages
+	Cluster  string   `json:"cluster"`
+	Handler  Handler  `json:"handler"`
+	Resource Resource `json:"resource"`
+	// Resources are watched through the dynamic client, as group/version/resource
+	// (e.g. cert-manager.io/v1/certificates), version/resource for the core group,
+	// or a resource name or short name resolved by discovery
+	Resources []string `json:"resources"`
 	Namespace string   `json:"namespace"`
+	// Selectors are keyed by resource flag (e.g. po) or entry of Resources
+	Selectors  map[string]Selector `json:"selectors"`
+	Namespaces Namespaces          `json:"namespaces"`
+	Diff       Diff                `json:"diff"`
+	Updates    []Update            `json:"updates"`
+	// Filters let an event through when any of them matches, every event if empty
+	Filters        []Filter       `json:"filters"`
+	Analyzers      Analyzers      `json:"analyzers"`
+	Events         Events         `json:"events"`
+	Enrichment     Enrichment     `json:"enrichment"`
+	LeaderElection LeaderElection `json:"leaderelection"`
+	Server         Server         `json:"server"`
+	Shutdown       Shutdown       `json:"shutdown"`
+	// Queue applies to every resource, Queues override it by resource flag
+	// (e.g. po), entry of Resources or analyzer name (e.g. pod-health)
+	Queue  Queue            `json:"queue"`
+	Queues map[string]Queue `json:"queues"`
 }
 
 // New creates new config object
@@ -82,13 +308,67 @@ func (c *Config) Load() error {
 	}
 
 	if len(b) != 0 {
-		return yaml.Unmarshal(b, c)
+		if err := yaml.Unmarshal(b, c); err != nil {
+			return err
+		}
 	}
 
+	return c.validate()
+
+}
+
+// validate compiles the filters and parses the durations
+// so a broken configuration fails at load
+func (c *Config) validate() error {
+	for i, f := range c.Filters {
+		if _, err := filter.New(f.Name, f.Expression); err != nil {
+			return fmt.Errorf("invalid filter %d (%s): %v", i, f.Name, err)
+		}
+	}
+	for name, d := range map[string]string{
+		"leaseduration": c.LeaderElection.LeaseDuration,
+		"renewdeadline": c.LeaderElection.RenewDeadline,
+		"retryperiod":   c.LeaderElection.RetryPeriod,
+	} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return fmt.Errorf("invalid %s of leader election: %v", name, err)
+		}
+	}
+	if d := c.Shutdown.DrainTimeout; d != "" {
+		if _, err := time.ParseDuration(d); err != nil {
+			return fmt.Errorf("invalid draintimeout of shutdown: %v", err)
+		}
+	}
+	if err := c.Queue.validate(); err != nil {
+		return fmt.Errorf("invalid queue: %v", err)
+	}
+	for key, q := range c.Queues {
+		if err := q.validate(); err != nil {
+			return fmt.Errorf("invalid queue of %s: %v", key, err)
+		}
+	}
 	return nil
+}
 
+func (q Queue) validate() error {
+	if q.Workers < 0 || q.QPS < 0 || q.Burst < 0 {
+		return fmt.Errorf("workers, qps and burst must not be negative")
+	}
+	for name, d := range map[string]string{"basedelay": q.BaseDelay, "maxdelay": q.MaxDelay} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+	}
+	return nil
 }
 
+// Write writes configuration to config file
 func (c *Config) Write() error {
 	b, err := yaml.Marshal(c)
 	if err != nil {
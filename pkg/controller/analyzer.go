@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+	"github.com/walk1ng/k8swatch/pkg/metrics"
+	"github.com/walk1ng/k8swatch/pkg/utils"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// analyzer derives notifications from a change of an object,
+// old is nil when the object is created and new is nil when it is deleted
+type analyzer func(old, new interface{}) []handlers.Notification
+
+// analyzers run on top of the informers of the registry,
+// they only fire on transitions and never on resyncs
+var analyzers = []struct {
+	// key is the resource flag of the analyzed resource
+	key     string
+	name    string
+	enabled func(a *config.Analyzers) bool
+	// newAnalyzer gets the factory of the analyzed informer to look up related objects,
+	// it returns the caches of the lookups along with the analyzer
+	newAnalyzer func(f informers.SharedInformerFactory) (analyzer, []cache.InformerSynced)
+}{
+	{
+		"po",
+		"pod-health",
+		func(a *config.Analyzers) bool { return a.PodHealth },
+		func(informers.SharedInformerFactory) (analyzer, []cache.InformerSynced) { return analyzePod, nil },
+	},
+	{
+		"deploy",
+		"deployment-rollout",
+		func(a *config.Analyzers) bool { return a.Rollout },
+		func(f informers.SharedInformerFactory) (analyzer, []cache.InformerSynced) {
+			replicaSets := f.Apps().V1().ReplicaSets()
+			return newRolloutAnalyzer(deploymentRollout(replicaSets.Lister())), []cache.InformerSynced{replicaSets.Informer().HasSynced}
+		},
+	},
+	{
+		"ds",
+		"daemonset-rollout",
+		func(a *config.Analyzers) bool { return a.Rollout },
+		func(informers.SharedInformerFactory) (analyzer, []cache.InformerSynced) {
+			return newRolloutAnalyzer(daemonSetRollout), nil
+		},
+	},
+	{
+		"sts",
+		"statefulset-rollout",
+		func(a *config.Analyzers) bool { return a.Rollout },
+		func(informers.SharedInformerFactory) (analyzer, []cache.InformerSynced) {
+			return newRolloutAnalyzer(statefulSetRollout), nil
+		},
+	},
+}
+
+// newAnalyzerController creates a controller queueing the notifications of analyze,
+// it shares the informer with the controller of the raw events if there is one,
+// synced are the caches analyze looks up
+func newAnalyzerController(clientset kubernetes.Interface, eventHandler handlers.EventHandler, informer cache.SharedIndexInformer, gvr schema.GroupVersionResource, name string, analyze analyzer, synced []cache.InformerSynced, settings queueSettings, p *pipeline) *Controller {
+	queue := newShardedQueue(settings)
+
+	logger := logrus.WithField("pkg", "k8swatch-"+name)
+
+	enqueue := func(old, new interface{}) {
+		for _, n := range analyze(old, new) {
+			n.Owner = p.owners.resolve(n.Object)
+			newEvent, err := newNotificationEvent(name, n)
+			if err != nil {
+				logger.Errorf("Failed to build %s notification: %v", n.Reason, err)
+				continue
+			}
+			if !p.namespaces.allows(newEvent.namespace) {
+				continue
+			}
+			logger.Infof("Processing %s notification: %s", n.Reason, newEvent.key)
+			metrics.EventsReceived.WithLabelValues(utils.GetObjectKind(n.Object), "notification").Inc()
+			queue.Add(newEvent)
+			metrics.QueueDepth.WithLabelValues(name).Set(float64(queue.Len()))
+		}
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			// objects listed at startup were not created
+			if !isInInitialList {
+				enqueue(nil, obj)
+			}
+		},
+		UpdateFunc: func(old, new interface{}) {
+			enqueue(old, new)
+		},
+		DeleteFunc: func(obj interface{}) {
+			enqueue(deletedObject(obj), nil)
+		},
+	})
+
+	return &Controller{
+		name:         name,
+		logger:       logger,
+		clientset:    clientset,
+		queue:        queue,
+		maxRetries:   settings.maxRetries,
+		informer:     informer,
+		gvr:          gvr,
+		cluster:      p.cluster,
+		eventHandler: eventHandler,
+		owners:       p.owners,
+		synced:       synced,
+	}
+}
@@ -1,431 +1,385 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/walk1ng/k8swatch/pkg/config"
 	"github.com/walk1ng/k8swatch/pkg/handlers"
+	"github.com/walk1ng/k8swatch/pkg/metrics"
+	"github.com/walk1ng/k8swatch/pkg/server"
 	"github.com/walk1ng/k8swatch/pkg/utils"
 
-	apps_v1beta1 "k8s.io/api/apps/v1beta1"
-	batch_v1 "k8s.io/api/batch/v1"
-	api_v1 "k8s.io/api/core/v1"
-	ext_v1beta1 "k8s.io/api/extensions/v1beta1"
-	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/util/workqueue"
 )
 
-const maxRetries = 5
+// handlerTimeout bounds one delivery of an event to the handlers
+const handlerTimeout = 30 * time.Second
 
-var serverStartTime time.Time
+// defaultDrainTimeout bounds the delivery of queued events on shutdown
+const defaultDrainTimeout = 10 * time.Second
 
-// Event describe the informer event
-type Event struct {
-	key          string
-	eventType    string
-	resourceType string
-	namespace    string
-}
+var serverStartTime time.Time
 
 // Controller object
 type Controller struct {
-	logger       *logrus.Entry
-	clientset    kubernetes.Interface
-	queue        workqueue.RateLimitingInterface
-	informer     cache.SharedIndexInformer
-	eventHandler handlers.Handler
+	// name labels the metrics of the controller
+	name      string
+	logger    *logrus.Entry
+	clientset kubernetes.Interface
+	queue     *shardedQueue
+	// maxRetries bounds the retries of a failed delivery
+	maxRetries int
+	informer   cache.SharedIndexInformer
+	// gvr is the watched resource and cluster names the cluster, both told to the handlers
+	gvr          schema.GroupVersionResource
+	cluster      string
+	eventHandler handlers.EventHandler
+	// owners resolve the owners of the events, its caches have to sync as well
+	owners *ownerResolver
+	// synced are further caches the controller looks up, e.g. by an analyzer
+	synced []cache.InformerSynced
 }
 
 // Start starts controller entry
-func Start(conf *config.Config, eventHandler handlers.Handler) {
+func Start(conf *config.Config, eventHandler handlers.EventHandler) {
 	var clientset kubernetes.Interface
+	var dynamicClient dynamic.Interface
 	_, err := rest.InClusterConfig()
 	if err == rest.ErrNotInCluster {
 		// run out of cluster
 		clientset = utils.GetClientOutOfCluster()
+		if len(conf.Resources) != 0 {
+			dynamicClient = utils.GetDynamicClientOutOfCluster()
+		}
 	} else {
 		// run in cluster
 		clientset = utils.GetClient()
+		if len(conf.Resources) != 0 {
+			dynamicClient = utils.GetDynamicClient()
+		}
 	}
 
-	if conf.Resource.Pod {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.CoreV1().Pods(conf.Namespace).List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.CoreV1().Pods(conf.Namespace).Watch(options)
-				},
-			},
-			&api_v1.Pod{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "pod")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	drainTimeout, err := durationOrDefault(conf.Shutdown.DrainTimeout, defaultDrainTimeout)
+	if err != nil {
+		logrus.Fatalf("Invalid drain timeout: %v", err)
 	}
 
-	if conf.Resource.DaemonSet {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.ExtensionsV1beta1().DaemonSets(conf.Namespace).List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.ExtensionsV1beta1().DaemonSets(conf.Namespace).Watch(options)
-				},
-			},
-			&ext_v1beta1.DaemonSet{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "daemonset")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	// every controller shares the same lifecycle, which ends on SIGINT or SIGTERM,
+	// a second signal exits without waiting for the queued events
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		sigterm := make(chan os.Signal, 2)
+		signal.Notify(sigterm, syscall.SIGINT)
+		signal.Notify(sigterm, syscall.SIGTERM)
+		<-sigterm
+		logrus.Infof("Shutting down, delivering queued events for up to %v", drainTimeout)
+		cancel()
+		<-sigterm
+		logrus.Warn("Exiting without delivering queued events")
+		os.Exit(1)
+	}()
+
+	if conf.Server.Address != "" {
+		go server.Serve(ctx, conf.Server.Address, controllersReady.synced)
 	}
 
-	if conf.Resource.ReplicaSet {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.ExtensionsV1beta1().ReplicaSets(conf.Namespace).List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.ExtensionsV1beta1().ReplicaSets(conf.Namespace).Watch(options)
-				},
-			},
-			&ext_v1beta1.ReplicaSet{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "replicaset")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	// running is held by the controllers of a run until they drained their queues,
+	// a leader starts its run in the background of the election
+	var running sync.Mutex
+	run := func(ctx context.Context) {
+		running.Lock()
+		defer running.Unlock()
+		runControllers(ctx, conf, drainTimeout, clientset, dynamicClient, eventHandler)
 	}
-
-	if conf.Resource.Service {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.CoreV1().Services(conf.Namespace).List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.CoreV1().Services(conf.Namespace).Watch(options)
-				},
-			},
-			&api_v1.Service{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "service")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	if !conf.LeaderElection.Enabled {
+		run(ctx)
+	} else if err := runLeaderElection(ctx, clientset, conf.LeaderElection, run); err != nil {
+		logrus.Fatal(err)
 	}
+	running.Lock()
 
-	if conf.Resource.Deployment {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.AppsV1beta1().Deployments(conf.Namespace).List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.AppsV1beta1().Deployments(conf.Namespace).Watch(options)
-				},
-			},
-			&apps_v1beta1.Deployment{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "deployment")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	// the handlers get a drain timeout of their own to deliver what they buffered
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancelFlush()
+	if err := handlers.Shutdown(flushCtx, eventHandler); err != nil {
+		logrus.Errorf("Failed to shut handlers down: %v", err)
 	}
+	logrus.Info("Shut down")
+}
 
-	if conf.Resource.Namespace {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.CoreV1().Namespaces().List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.CoreV1().Namespaces().Watch(options)
-				},
-			},
-			&api_v1.Namespace{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "namespace")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
+// runControllers watches the configured resources until ctx is done, then waits
+// for the controllers to drain their queues, giving up after drainTimeout,
+// informers and controllers are built anew on every call
+func runControllers(ctx context.Context, conf *config.Config, drainTimeout time.Duration, clientset kubernetes.Interface, dynamicClient dynamic.Interface, eventHandler handlers.EventHandler) {
+	stopCh := ctx.Done()
 
-	if conf.Resource.ReplicationController {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.CoreV1().ReplicationControllers(conf.Namespace).List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.CoreV1().ReplicationControllers(conf.Namespace).Watch(options)
-				},
-			},
-			&api_v1.ReplicationController{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "replication controller")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
+	factories := newFactories(clientset, dynamicClient)
 
-	if conf.Resource.Job {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.BatchV1().Jobs(conf.Namespace).List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.BatchV1().Jobs(conf.Namespace).Watch(options)
-				},
-			},
-			&batch_v1.Job{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "job")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	p, err := newPipeline(conf, factories)
+	if err != nil {
+		logrus.Fatal(err)
 	}
 
-	if conf.Resource.PersistentVolume {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.CoreV1().PersistentVolumes().List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.CoreV1().PersistentVolumes().Watch(options)
-				},
-			},
-			&api_v1.PersistentVolume{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "persistent volume")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	// typedInformer returns the informer of r, the factory of its scope and the
+	// served resource, false if no version of r is served by the cluster
+	typedInformer := func(r resource) (cache.SharedIndexInformer, informers.SharedInformerFactory, schema.GroupVersionResource, bool) {
+		v, err := servedVersion(clientset.Discovery(), r)
+		if err == errNotServed {
+			logrus.Errorf("Failed to watch %s: no served api version of %s", r.resourceType, r.name)
+			return nil, nil, schema.GroupVersionResource{}, false
+		}
+		if err != nil {
+			logrus.Fatalf("Failed to watch %s: %v", r.resourceType, err)
+		}
+		gv, err := schema.ParseGroupVersion(v.groupVersion)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		scope, err := newListScope(conf, r.key, r.namespaced)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Infof("Watching %s through %s", r.resourceType, v.groupVersion)
+		factory := factories.forTyped(scope)
+		return v.informer(factory), factory, gv.WithResource(r.name), true
 	}
 
-	if conf.Resource.Secret {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.CoreV1().Secrets(conf.Namespace).List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.CoreV1().Secrets(conf.Namespace).Watch(options)
-				},
-			},
-			&api_v1.Secret{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "secret")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	// queue returns the queue settings of key, a resource flag, entry of Resources or analyzer name
+	queue := func(key string) queueSettings {
+		settings, err := newQueueSettings(conf, key)
+		if err != nil {
+			logrus.Fatalf("Invalid queue of %s: %v", key, err)
+		}
+		return settings
 	}
 
-	if conf.Resource.ConfigMap {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.CoreV1().ConfigMaps(conf.Namespace).List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.CoreV1().ConfigMaps(conf.Namespace).Watch(options)
-				},
-			},
-			&api_v1.ConfigMap{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "configmap")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	var controllers []*Controller
+	for _, r := range registry {
+		if !r.enabled(&conf.Resource) {
+			continue
+		}
+		informer, _, gvr, ok := typedInformer(r)
+		if !ok {
+			continue
+		}
+		switch r.key {
+		case "ev":
+			// events are aggregated into notifications rather than relayed as raw changes
+			controllers = append(controllers, newEventController(clientset, eventHandler, informer, gvr, conf.Events, queue(r.key), p))
+		case "no":
+			// nodes update their status constantly, only transitions are reported
+			controllers = append(controllers, newAnalyzerController(clientset, eventHandler, informer, gvr, r.resourceType, newNodeAnalyzer(), nil, queue(r.key), p))
+		default:
+			controllers = append(controllers, newController(clientset, eventHandler, informer, gvr, r.resourceType, queue(r.key), p))
+		}
 	}
 
-	if conf.Resource.Ingress {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return clientset.ExtensionsV1beta1().Ingresses(conf.Namespace).List(options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return clientset.ExtensionsV1beta1().Ingresses(conf.Namespace).Watch(options)
-				},
-			},
-			&ext_v1beta1.Ingress{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newController(clientset, eventHandler, informer, "ingress")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+	for _, a := range analyzers {
+		if !a.enabled(&conf.Analyzers) {
+			continue
+		}
+		if informer, factory, gvr, ok := typedInformer(lookup(a.key)); ok {
+			analyze, synced := a.newAnalyzer(factory)
+			controllers = append(controllers, newAnalyzerController(clientset, eventHandler, informer, gvr, a.name, analyze, synced, queue(a.name), p))
+		}
 	}
 
-	sigterm := make(chan os.Signal, 1)
-	signal.Notify(sigterm, syscall.SIGINT)
-	signal.Notify(sigterm, syscall.SIGTERM)
-	<-sigterm
+	if len(conf.Resources) != 0 {
+		resources, err := resolveResources(clientset.Discovery(), conf.Resources)
+		if err != nil {
+			logrus.Fatal(err)
+		}
 
+		for _, resource := range resources {
+			scope, err := newListScope(conf, resource.key, resource.namespaced)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			informer := factories.forDynamic(scope).ForResource(resource.gvr).Informer()
+			controllers = append(controllers, newController(clientset, eventHandler, informer, resource.gvr, resource.String(), queue(resource.key), p))
+		}
+	}
+
+	serverStartTime = time.Now().Local()
+	factories.start(stopCh)
+
+	var drained sync.WaitGroup
+	for _, c := range controllers {
+		drained.Add(1)
+		go func(c *Controller) {
+			defer drained.Done()
+			c.Run(stopCh)
+		}(c)
+	}
+	controllersReady.set(controllers)
+
+	<-stopCh
+	controllersReady.set(nil)
+
+	done := make(chan struct{})
+	go func() {
+		drained.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		logrus.Info("Controllers stopped, queued events delivered")
+	case <-time.After(drainTimeout):
+		pending := 0
+		for _, c := range controllers {
+			pending += c.queue.Len()
+		}
+		logrus.Warnf("Controllers still draining after %v, %d queued events not delivered", drainTimeout, pending)
+	}
 }
 
-func newController(clientset kubernetes.Interface, eventHandler handlers.Handler, informer cache.SharedIndexInformer, resourceType string) *Controller {
-	// queue
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+func newController(clientset kubernetes.Interface, eventHandler handlers.EventHandler, informer cache.SharedIndexInformer, gvr schema.GroupVersionResource, resourceType string, settings queueSettings, p *pipeline) *Controller {
+	queue := newShardedQueue(settings)
 
-	var newEvent Event
-	var err error
+	logger := logrus.WithField("pkg", "k8swatch-"+resourceType)
+
+	// every callback builds its own event, callbacks never share state
+	enqueue := func(eventType string, obj, oldObj interface{}) {
+		metrics.EventsReceived.WithLabelValues(utils.GetObjectKind(deletedObject(obj)), eventType).Inc()
+		newEvent, skip, err := p.process(eventType, resourceType, obj, oldObj)
+		if err != nil {
+			logger.Errorf("Failed to build %s event for %s: %v", eventType, resourceType, err)
+			return
+		}
+		if skip != "" {
+			logger.Debugf("Skipping %s to %s: %s", eventType, resourceType, skip)
+			return
+		}
+		logger.Infof("Processing %s to %s: %s", eventType, resourceType, newEvent.key)
+		queue.Add(newEvent)
+		metrics.QueueDepth.WithLabelValues(resourceType).Set(float64(queue.Len()))
+	}
 
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
-			newEvent.key, err = cache.MetaNamespaceKeyFunc(obj)
-			newEvent.eventType = "create"
-			newEvent.resourceType = resourceType
-			logrus.WithField("pkg", "k8swatch-"+resourceType).Infof("Processing add to %s: %s", resourceType, newEvent.key)
-			if err == nil {
-				queue.Add(newEvent)
-			}
+			enqueue("create", obj, nil)
 		},
 		UpdateFunc: func(old, new interface{}) {
-			newEvent.key, err = cache.MetaNamespaceKeyFunc(old)
-			newEvent.eventType = "update"
-			newEvent.resourceType = resourceType
-			logrus.WithField("pkg", "k8swatch-"+resourceType).Infof("Processing update to %s: %s", resourceType, newEvent.key)
-			if err == nil {
-				queue.Add(newEvent)
-			}
+			enqueue("update", new, old)
 		},
 		DeleteFunc: func(obj interface{}) {
-			newEvent.key, err = cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
-			newEvent.eventType = "delete"
-			newEvent.resourceType = resourceType
-			newEvent.namespace = utils.GetObjectMetaData(obj).Namespace
-			logrus.WithField("pkg", "k8swatch-"+resourceType).Infof("Processing delete to %s: %s", resourceType, newEvent.key)
-			if err == nil {
-				queue.Add(newEvent)
-			}
+			// the object is already gone from the indexer when processed,
+			// so the last known state (possibly a tombstone) travels with the event
+			enqueue("delete", obj, nil)
 		},
 	})
 
 	return &Controller{
-		logger:       logrus.WithField("pkg", "k8swatch-"+resourceType),
+		name:         resourceType,
+		logger:       logger,
 		clientset:    clientset,
 		queue:        queue,
+		maxRetries:   settings.maxRetries,
 		informer:     informer,
+		gvr:          gvr,
+		cluster:      p.cluster,
 		eventHandler: eventHandler,
+		owners:       p.owners,
 	}
 }
 
-// Run runs the controller
+// Run runs the controller until stopCh is closed, then returns
+// once the events already queued are processed
 func (c *Controller) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
-	defer c.queue.ShutDown()
 
 	c.logger.Info("Starting k8swatch controller")
-	serverStartTime = time.Now().Local()
-
-	go c.informer.Run(stopCh)
 
 	if !cache.WaitForCacheSync(stopCh, c.hasSynced) {
+		c.queue.ShutDown()
 		utilruntime.HandleError(fmt.Errorf("Timeout waiting for caches to sync"))
 		return
 	}
 
 	c.logger.Info("k8swatch controller synced and ready")
 
-	wait.Until(c.runWorker, time.Second, stopCh)
+	go func() {
+		<-stopCh
+		// the informers are stopped, the queue only hands out what it holds,
+		// events failing from now on are not retried
+		c.queue.ShutDown()
+	}()
+
+	var workers sync.WaitGroup
+	for _, s := range c.queue.shards {
+		workers.Add(1)
+		go func(s *shard) {
+			defer utilruntime.HandleCrash()
+			defer workers.Done()
+			c.runWorker(s)
+		}(s)
+	}
+	workers.Wait()
 
+	c.logger.Info("k8swatch controller stopped")
 }
 
+// hasSynced reports whether the informer of the controller, the owner caches
+// and the further caches of the controller synced
 func (c *Controller) hasSynced() bool {
-	return c.informer.HasSynced()
+	if !c.informer.HasSynced() || !c.owners.hasSynced() {
+		return false
+	}
+	for _, synced := range c.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
 }
 
-func (c *Controller) runWorker() {
-	for c.processNextItem() {
+// runWorker processes the events of one shard of the queue, then
+// delivers once the events still waiting for a retry on shutdown
+func (c *Controller) runWorker(queue *shard) {
+	for c.processNextItem(queue) {
+	}
+	for _, newEvent := range queue.leftovers() {
+		if err := c.processItem(newEvent); err != nil {
+			c.logger.Errorf("Error processing %s (give up): %v", newEvent.key, err)
+			metrics.QueueGiveUps.WithLabelValues(c.name).Inc()
+			utilruntime.HandleError(err)
+		}
 	}
 }
 
-func (c *Controller) processNextItem() bool {
-	newEvent, quit := c.queue.Get()
-
-	if quit {
+func (c *Controller) processNextItem(queue *shard) bool {
+	newEvent, ok := queue.next()
+	if !ok {
 		return false
 	}
 
-	defer c.queue.Done(newEvent)
-	err := c.processItem(newEvent.(Event))
+	defer queue.Done(newEvent)
+	metrics.QueueDepth.WithLabelValues(c.name).Set(float64(c.queue.Len()))
+	err := c.processItem(newEvent)
 	if err == nil {
-		c.queue.Forget(newEvent)
-	} else if c.queue.NumRequeues(newEvent) < maxRetries {
-		c.logger.Errorf("Error processing %s (will retry): %v", newEvent.(Event).key, err)
-		c.queue.AddRateLimited(newEvent)
+		queue.finish(newEvent)
+	} else if !handlers.IsPermanent(err) && queue.NumRequeues(newEvent) < c.maxRetries && queue.retry(newEvent) {
+		c.logger.Errorf("Error processing %s (will retry): %v", newEvent.key, err)
+		metrics.QueueRetries.WithLabelValues(c.name).Inc()
 	} else {
 		// too many retries and err != nil
-		c.logger.Errorf("Error processing %s (give up): %v", newEvent.(Event).key, err)
-		c.queue.Forget(newEvent)
+		c.logger.Errorf("Error processing %s (give up): %v", newEvent.key, err)
+		metrics.QueueGiveUps.WithLabelValues(c.name).Inc()
+		queue.finish(newEvent)
 		utilruntime.HandleError(err)
 	}
 
@@ -433,22 +387,25 @@ func (c *Controller) processNextItem() bool {
 }
 
 func (c *Controller) processItem(newEvent Event) error {
-	obj, _, err := c.informer.GetIndexer().GetByKey(newEvent.key)
-	if err != nil {
-		return fmt.Errorf("Error fetching object with key %s from store: %v", newEvent.key, err)
-	}
-
-	// get object's metadata
-	objMeta := utils.GetObjectMetaData(obj)
-
-	// process event based on its type
-	switch newEvent.eventType {
-	case "create":
-		if objMeta.CreationTimestamp.Sub(serverStartTime).Seconds() > 0 {
-			c.eventHandler.ObjCreated(obj)
+	// the event carries its own snapshots so the store is not consulted,
+	// objects which existed before k8swatch started are not reported as created
+	if newEvent.eventType == "create" {
+		objMeta := utils.GetObjectMetaData(newEvent.obj)
+		if objMeta.CreationTimestamp.Sub(serverStartTime).Seconds() <= 0 {
 			return nil
 		}
+	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
+	defer cancel()
+	if err := c.eventHandler.Handle(ctx, c.envelope(newEvent)); err != nil {
+		return err
 	}
+	dispatched(newEvent)
 	return nil
 }
+
+// dispatched counts an event handed to the handlers
+func dispatched(e Event) {
+	metrics.EventsDispatched.WithLabelValues(utils.GetObjectKind(deletedObject(e.obj)), e.eventType).Inc()
+}
@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// nopHandler accepts every event
+type nopHandler struct{}
+
+func (nopHandler) Init(c *config.Config) error                        { return nil }
+func (nopHandler) Handle(ctx context.Context, e handlers.Event) error { return nil }
+
+func TestControllerQueuesConcurrentChanges(t *testing.T) {
+	const pods = 30
+
+	clientset := fake.NewSimpleClientset()
+	factories := newFactories(clientset, nil)
+	p, err := newPipeline(&config.Config{}, factories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := newQueueSettings(&config.Config{Queue: config.Queue{Workers: 4}}, "po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	informer := factories.forTyped(listScope{}).Core().V1().Pods().Informer()
+	c := newController(clientset, nopHandler{}, informer, schema.GroupVersionResource{Version: "v1", Resource: "pods"}, "pod", settings, p)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factories.start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.hasSynced) {
+		t.Fatal("caches did not sync")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < pods; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := context.Background()
+			pod := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{
+				Namespace:       fmt.Sprintf("ns-%d", i%3),
+				Name:            fmt.Sprintf("pod-%d", i),
+				UID:             types.UID(fmt.Sprintf("uid-%d", i)),
+				ResourceVersion: "1",
+			}}
+			pods := clientset.CoreV1().Pods(pod.Namespace)
+			if _, err := pods.Create(ctx, pod, meta_v1.CreateOptions{}); err != nil {
+				t.Error(err)
+				return
+			}
+			// the fake clientset leaves resourceVersion to the caller
+			updated := pod.DeepCopy()
+			updated.ResourceVersion = "2"
+			updated.Labels = map[string]string{"app": "web"}
+			if _, err := pods.Update(ctx, updated, meta_v1.UpdateOptions{}); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := pods.Delete(ctx, pod.Name, meta_v1.DeleteOptions{}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.queue.Len() < 3*pods && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := c.queue.Len(); n != 3*pods {
+		t.Fatalf("queued %d events, want %d", n, 3*pods)
+	}
+
+	eventTypes := map[string][]string{}
+	for _, s := range c.queue.shards {
+		for n := s.len(); n > 0; n-- {
+			e, ok := s.next()
+			if !ok {
+				t.Fatal("shard shut down")
+			}
+			s.Done(e)
+
+			var i int
+			if _, err := fmt.Sscanf(e.key, "ns-%d/pod-%d", new(int), &i); err != nil {
+				t.Fatalf("unexpected key %q", e.key)
+			}
+			if want := fmt.Sprintf("ns-%d/pod-%d", i%3, i); e.key != want {
+				t.Errorf("key = %q, want %q", e.key, want)
+			}
+			if want := fmt.Sprintf("ns-%d", i%3); e.namespace != want {
+				t.Errorf("namespace of %s = %q, want %q", e.key, e.namespace, want)
+			}
+			if want := types.UID(fmt.Sprintf("uid-%d", i)); e.uid != want {
+				t.Errorf("uid of %s = %q, want %q", e.key, e.uid, want)
+			}
+			if e.resourceType != "pod" || e.id == "" {
+				t.Errorf("event of %s has resourceType %q and id %q", e.key, e.resourceType, e.id)
+			}
+			eventTypes[e.key] = append(eventTypes[e.key], e.eventType)
+		}
+	}
+
+	if len(eventTypes) != pods {
+		t.Errorf("queued events of %d pods, want %d", len(eventTypes), pods)
+	}
+	for key, got := range eventTypes {
+		if want := []string{"create", "update", "delete"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("events of %s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+// gatedHandler records the names of the objects it handles,
+// the first event blocks until gate is closed
+type gatedHandler struct {
+	mu      sync.Mutex
+	gate    chan struct{}
+	handled []string
+}
+
+func (h *gatedHandler) Init(c *config.Config) error { return nil }
+
+func (h *gatedHandler) Handle(ctx context.Context, e handlers.Event) error {
+	h.mu.Lock()
+	first := len(h.handled) == 0
+	h.handled = append(h.handled, e.Name)
+	h.mu.Unlock()
+	if first {
+		<-h.gate
+	}
+	return nil
+}
+
+func (h *gatedHandler) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.handled)
+}
+
+func TestControllerRunDrainsQueue(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	factories := newFactories(clientset, nil)
+	p, err := newPipeline(&config.Config{}, factories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := newQueueSettings(&config.Config{}, "po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &gatedHandler{gate: make(chan struct{})}
+	informer := factories.forTyped(listScope{}).Core().V1().Pods().Informer()
+	c := newController(clientset, h, informer, schema.GroupVersionResource{Version: "v1", Resource: "pods"}, "pod", settings, p)
+
+	stopCh := make(chan struct{})
+	factories.start(stopCh)
+	stopped := make(chan struct{})
+	go func() {
+		c.Run(stopCh)
+		close(stopped)
+	}()
+
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, name := range names {
+		pod := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: name, CreationTimestamp: meta_v1.Now()}}
+		if _, err := clientset.CoreV1().Pods("default").Create(context.Background(), pod, meta_v1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the worker is stuck on the first event, the others wait in the queue
+	deadline := time.Now().Add(5 * time.Second)
+	for (c.queue.Len() < len(names)-1 || h.count() == 0) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := c.queue.Len(); n != len(names)-1 {
+		t.Fatalf("queued %d events, want %d", n, len(names)-1)
+	}
+
+	close(stopCh)
+	select {
+	case <-stopped:
+		t.Fatal("Run() returned before the queued events were delivered")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(h.gate)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return once the queue drained")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !reflect.DeepEqual(h.handled, names) {
+		t.Errorf("handled %v, want %v", h.handled, names)
+	}
+}
+
+func TestAnalyzerControllerWaitsForLookups(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	factories := newFactories(clientset, nil)
+	p, err := newPipeline(&config.Config{}, factories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := newQueueSettings(&config.Config{}, "deployment-rollout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory := factories.forTyped(listScope{})
+	informer := factory.Apps().V1().Deployments().Informer()
+	var c *Controller
+	for _, a := range analyzers {
+		if a.name == "deployment-rollout" {
+			analyze, synced := a.newAnalyzer(factory)
+			c = newAnalyzerController(clientset, nopHandler{}, informer, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, a.name, analyze, synced, settings, p)
+		}
+	}
+	if c == nil {
+		t.Fatal("no deployment-rollout analyzer")
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+		t.Fatal("deployment cache did not sync")
+	}
+	if c.hasSynced() {
+		t.Error("hasSynced() = true before the ReplicaSet cache started, want false")
+	}
+
+	factories.start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.hasSynced) {
+		t.Error("hasSynced() = false after the ReplicaSet cache started, want true")
+	}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+)
+
+// dynamicResource is a resource of config.Resources resolved by discovery
+type dynamicResource struct {
+	// key is the entry of config.Resources
+	key        string
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+func (r dynamicResource) String() string {
+	if r.gvr.Group == "" {
+		return r.gvr.Version + "/" + r.gvr.Resource
+	}
+	return r.gvr.Group + "/" + r.gvr.Version + "/" + r.gvr.Resource
+}
+
+// resolveResources resolves the resource strings of the config, which are
+// either group/version/resource, version/resource for the core group,
+// or a resource name, short name or kind such as deploy or certificates.cert-manager.io
+func resolveResources(client discovery.DiscoveryInterface, resources []string) ([]dynamicResource, error) {
+	groupResources, err := restmapper.GetAPIGroupResources(client)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to discover api resources: %v", err)
+	}
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDiscoveryRESTMapper(groupResources), client, func(msg string) {
+		logrus.Warn(msg)
+	})
+
+	var resolved []dynamicResource
+	for _, resource := range resources {
+		gvr, err := mapper.ResourceFor(parseResource(resource))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to resolve resource %q: %v", resource, err)
+		}
+		gvk, err := mapper.KindFor(gvr)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to resolve kind of resource %q: %v", resource, err)
+		}
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to resolve scope of resource %q: %v", resource, err)
+		}
+		resolved = append(resolved, dynamicResource{
+			key:        resource,
+			gvr:        gvr,
+			namespaced: mapping.Scope.Name() == meta.RESTScopeNameNamespace,
+		})
+	}
+	return resolved, nil
+}
+
+// parseResource parses a resource string into a possibly partial GroupVersionResource
+func parseResource(resource string) schema.GroupVersionResource {
+	parts := strings.Split(resource, "/")
+	switch len(parts) {
+	case 3:
+		return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}
+	case 2:
+		return schema.GroupVersionResource{Version: parts[0], Resource: parts[1]}
+	default:
+		return schema.ParseGroupResource(resource).WithVersion("")
+	}
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"testing"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeDiscovery serves the resources by group version
+func fakeDiscovery(resources ...*meta_v1.APIResourceList) *fakediscovery.FakeDiscovery {
+	d := fake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	d.Resources = resources
+	return d
+}
+
+var servedResources = []*meta_v1.APIResourceList{
+	{
+		GroupVersion: "v1",
+		APIResources: []meta_v1.APIResource{
+			{Name: "pods", Kind: "Pod", Namespaced: true, ShortNames: []string{"po"}},
+			{Name: "nodes", Kind: "Node", ShortNames: []string{"no"}},
+		},
+	},
+	{
+		GroupVersion: "apps/v1",
+		APIResources: []meta_v1.APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true, ShortNames: []string{"deploy"}}},
+	},
+	{
+		GroupVersion: "cert-manager.io/v1",
+		APIResources: []meta_v1.APIResource{{Name: "certificates", Kind: "Certificate", Namespaced: true, ShortNames: []string{"cert"}}},
+	},
+}
+
+func TestParseResource(t *testing.T) {
+	tests := []struct {
+		resource string
+		want     schema.GroupVersionResource
+	}{
+		{"cert-manager.io/v1/certificates", schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}},
+		{"v1/pods", schema.GroupVersionResource{Version: "v1", Resource: "pods"}},
+		{"certificates.cert-manager.io", schema.GroupVersionResource{Group: "cert-manager.io", Resource: "certificates"}},
+		{"deploy", schema.GroupVersionResource{Resource: "deploy"}},
+	}
+	for _, test := range tests {
+		if got := parseResource(test.resource); got != test.want {
+			t.Errorf("parseResource(%q) = %v, want %v", test.resource, got, test.want)
+		}
+	}
+}
+
+func TestResolveResources(t *testing.T) {
+	tests := []struct {
+		resource       string
+		want           string
+		wantNamespaced bool
+		wantErr        bool
+	}{
+		{"cert-manager.io/v1/certificates", "cert-manager.io/v1/certificates", true, false},
+		{"certificates.cert-manager.io", "cert-manager.io/v1/certificates", true, false},
+		{"v1/nodes", "v1/nodes", false, false},
+		{"deployments", "apps/v1/deployments", true, false},
+		{"Certificate", "cert-manager.io/v1/certificates", true, false},
+		{"deploy", "apps/v1/deployments", true, false},
+		{"widgets", "", false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.resource, func(t *testing.T) {
+			resolved, err := resolveResources(fakeDiscovery(servedResources...), []string{test.resource})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("resolveResources() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			r := resolved[0]
+			if r.key != test.resource || r.String() != test.want || r.namespaced != test.wantNamespaced {
+				t.Errorf("resolveResources() = %s %s namespaced %v, want %s %s namespaced %v",
+					r.key, r, r.namespaced, test.resource, test.want, test.wantNamespaced)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/diff"
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+	"github.com/walk1ng/k8swatch/pkg/utils"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Event describe the informer event
+// an Event is built once per informer callback and never modified
+// afterwards, it is queued by value together with snapshots of the objects
+type Event struct {
+	// id tells the handlers the retries of the event apart from other events
+	id           string
+	key          string
+	eventType    string
+	resourceType string
+	namespace    string
+	uid          types.UID
+	obj          interface{}
+	oldObj       interface{}
+	diff         *diff.Diff
+	owner        *handlers.Owner
+	notification *handlers.Notification
+	observed     time.Time
+	// seq orders the events of a queue, set once queued
+	seq uint64
+}
+
+// newEvent builds the event of an informer callback, oldObj and changes are only set for updates
+func newEvent(eventType, resourceType string, obj, oldObj interface{}, changes *diff.Diff, owner *handlers.Owner) (Event, error) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return Event{}, err
+	}
+
+	objMeta := utils.GetObjectMetaData(deletedObject(obj))
+	return Event{
+		id:           string(uuid.NewUUID()),
+		key:          key,
+		eventType:    eventType,
+		resourceType: resourceType,
+		namespace:    objMeta.Namespace,
+		uid:          objMeta.UID,
+		obj:          snapshot(obj),
+		oldObj:       snapshot(oldObj),
+		diff:         changes,
+		owner:        owner,
+		observed:     time.Now(),
+	}, nil
+}
+
+// newNotificationEvent builds the event of a notification derived by an analyzer
+func newNotificationEvent(resourceType string, n handlers.Notification) (Event, error) {
+	key, err := cache.MetaNamespaceKeyFunc(n.Object)
+	if err != nil {
+		return Event{}, err
+	}
+
+	n.Object = snapshot(n.Object)
+	objMeta := utils.GetObjectMetaData(n.Object)
+	return Event{
+		id:           string(uuid.NewUUID()),
+		key:          key,
+		eventType:    "notification",
+		resourceType: resourceType,
+		namespace:    objMeta.Namespace,
+		uid:          objMeta.UID,
+		obj:          n.Object,
+		owner:        n.Owner,
+		notification: &n,
+		observed:     time.Now(),
+	}, nil
+}
+
+// envelope returns the event handed to the handlers
+func (c *Controller) envelope(e Event) handlers.Event {
+	obj := deletedObject(e.obj)
+	return handlers.Event{
+		ID:           e.id,
+		Type:         e.eventType,
+		Kind:         utils.GetObjectKind(obj),
+		GVR:          c.gvr,
+		Namespace:    e.namespace,
+		Name:         utils.GetObjectMetaData(obj).Name,
+		Object:       obj,
+		OldObject:    e.oldObj,
+		Diff:         e.diff,
+		Owner:        e.owner,
+		Notification: e.notification,
+		Cluster:      c.cluster,
+		Timestamp:    e.observed,
+	}
+}
+
+// snapshot copies obj, objects handed out by informers are shared with the cache
+func snapshot(obj interface{}) interface{} {
+	switch object := obj.(type) {
+	case runtime.Object:
+		return object.DeepCopyObject()
+	case cache.DeletedFinalStateUnknown:
+		return cache.DeletedFinalStateUnknown{Key: object.Key, Obj: snapshot(object.Obj)}
+	}
+	return obj
+}
+
+// deletedObject unwraps the final state of an object from a
+// DeletedFinalStateUnknown tombstone, which the informer hands out
+// when the watch missed the delete
+func deletedObject(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// recordingHandler keeps the events it is handed and fails with err
+type recordingHandler struct {
+	events []handlers.Event
+	err    error
+}
+
+func (h *recordingHandler) Init(c *config.Config) error { return nil }
+
+func (h *recordingHandler) Handle(ctx context.Context, e handlers.Event) error {
+	h.events = append(h.events, e)
+	return h.err
+}
+
+func TestProcessItem(t *testing.T) {
+	created := meta_v1.NewTime(time.Now().Add(time.Hour))
+	pod := func(name string, creationTimestamp meta_v1.Time) *api_v1.Pod {
+		return &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: name, CreationTimestamp: creationTimestamp}}
+	}
+
+	tests := []struct {
+		name      string
+		eventType string
+		obj       interface{}
+		oldObj    interface{}
+		wantName  string
+		wantOld   bool
+	}{
+		{"create", "create", pod("web", created), nil, "web", false},
+		{"create of an object listed at startup", "create", pod("web", meta_v1.Time{}), nil, "", false},
+		{"update", "update", pod("web", created), pod("web", created), "web", true},
+		{"delete", "delete", pod("web", created), nil, "web", false},
+		{"delete of a tombstone", "delete", cache.DeletedFinalStateUnknown{Key: "default/web", Obj: pod("web", created)}, nil, "web", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := &recordingHandler{}
+			c := &Controller{eventHandler: h, gvr: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, cluster: "prod"}
+			e, err := newEvent(test.eventType, "pod", test.obj, test.oldObj, nil, nil)
+			if err != nil {
+				t.Fatalf("newEvent() = %v", err)
+			}
+			if err := c.processItem(e); err != nil {
+				t.Fatalf("processItem() = %v", err)
+			}
+
+			if test.wantName == "" {
+				if len(h.events) != 0 {
+					t.Errorf("handled %d events, want none", len(h.events))
+				}
+				return
+			}
+			if len(h.events) != 1 {
+				t.Fatalf("handled %d events, want 1", len(h.events))
+			}
+			got := h.events[0]
+			if got.ID != e.id || got.Type != test.eventType || got.Name != test.wantName || got.Namespace != "default" || got.Cluster != "prod" || got.GVR.Resource != "pods" {
+				t.Errorf("handled %+v, want the %s of default/%s", got, test.eventType, test.wantName)
+			}
+			if _, ok := got.Object.(*api_v1.Pod); !ok {
+				t.Errorf("Object = %T, want *v1.Pod", got.Object)
+			}
+			if (got.OldObject != nil) != test.wantOld {
+				t.Errorf("OldObject = %v, want it set %v", got.OldObject, test.wantOld)
+			}
+		})
+	}
+}
+
+func TestProcessItemError(t *testing.T) {
+	failed := errors.New("unavailable")
+	c := &Controller{eventHandler: &recordingHandler{err: failed}}
+	e, err := newEvent("delete", "pod", &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "web"}}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.processItem(e); err != failed {
+		t.Errorf("processItem() = %v, want %v", err, failed)
+	}
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+	"github.com/walk1ng/k8swatch/pkg/metrics"
+	"github.com/walk1ng/k8swatch/pkg/utils"
+
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// eventFilter selects the core/v1 Events worth a notification
+type eventFilter struct {
+	types         []string
+	reasons       []string
+	involvedKinds []string
+}
+
+func newEventFilter(conf config.Events) eventFilter {
+	types := conf.Types
+	if len(types) == 0 {
+		types = []string{api_v1.EventTypeWarning}
+	}
+	return eventFilter{
+		types:         types,
+		reasons:       conf.Reasons,
+		involvedKinds: conf.InvolvedKinds,
+	}
+}
+
+func (f eventFilter) matches(e *api_v1.Event) bool {
+	return utils.ContainsFold(f.types, e.Type) &&
+		(len(f.reasons) == 0 || utils.ContainsFold(f.reasons, e.Reason)) &&
+		(len(f.involvedKinds) == 0 || utils.ContainsFold(f.involvedKinds, e.InvolvedObject.Kind))
+}
+
+// eventAggregator collapses the repetitions of an event, the api server
+// bumps count (or series.count) of one Event object instead of creating a new one
+type eventAggregator struct {
+	mu sync.Mutex
+	// notified is the occurrence count at the last notification of each event
+	notified map[types.UID]int32
+}
+
+// observe records the occurrences of e and reports whether they deserve a notification:
+// the first occurrence, then every time the count doubled since the last notification
+func (a *eventAggregator) observe(e *api_v1.Event) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	count := occurrences(e)
+	last, ok := a.notified[e.UID]
+	if ok && count < 2*last {
+		return false
+	}
+	a.notified[e.UID] = count
+	return true
+}
+
+// seen records the occurrences of e without notifying, e.g. for events listed at startup
+func (a *eventAggregator) seen(e *api_v1.Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.notified[e.UID] = occurrences(e)
+}
+
+func (a *eventAggregator) forget(uid types.UID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.notified, uid)
+}
+
+// occurrences returns how many times e happened,
+// events.k8s.io clients count in series while the legacy recorder uses count
+func occurrences(e *api_v1.Event) int32 {
+	count := e.Count
+	if e.Series != nil && e.Series.Count > count {
+		count = e.Series.Count
+	}
+	if count < 1 {
+		count = 1
+	}
+	return count
+}
+
+// eventNotification describes e through its involved object
+func eventNotification(e *api_v1.Event) handlers.Notification {
+	severity := handlers.SeverityInfo
+	if e.Type == api_v1.EventTypeWarning {
+		severity = handlers.SeverityWarning
+	}
+
+	involved := e.InvolvedObject.Name
+	if e.InvolvedObject.Namespace != "" {
+		involved = e.InvolvedObject.Namespace + "/" + involved
+	}
+	message := fmt.Sprintf("%s %s: %s", e.InvolvedObject.Kind, involved, strings.TrimSpace(e.Message))
+	if count := occurrences(e); count > 1 {
+		message += fmt.Sprintf(" (x%d)", count)
+	}
+
+	return handlers.Notification{
+		Reason:   e.Reason,
+		Message:  message,
+		Severity: severity,
+		Object:   e,
+	}
+}
+
+// newEventController creates a controller turning core/v1 Events into notifications,
+// repetitions of an event are aggregated instead of relayed as raw updates
+func newEventController(clientset kubernetes.Interface, eventHandler handlers.EventHandler, informer cache.SharedIndexInformer, gvr schema.GroupVersionResource, conf config.Events, settings queueSettings, p *pipeline) *Controller {
+	queue := newShardedQueue(settings)
+
+	logger := logrus.WithField("pkg", "k8swatch-event")
+
+	filter := newEventFilter(conf)
+	aggregator := &eventAggregator{notified: map[types.UID]int32{}}
+
+	enqueue := func(eventType string, obj interface{}) {
+		e, ok := obj.(*api_v1.Event)
+		if !ok {
+			return
+		}
+		metrics.EventsReceived.WithLabelValues("Event", eventType).Inc()
+		if !filter.matches(e) || !p.namespaces.allows(e.Namespace) {
+			return
+		}
+		if !aggregator.observe(e) {
+			logger.Debugf("Aggregating %s of %s/%s (x%d)", e.Reason, e.Namespace, e.Name, occurrences(e))
+			return
+		}
+		n := eventNotification(e)
+		n.Owner = p.owners.resolveReference(e.InvolvedObject)
+		newEvent, err := newNotificationEvent("event", n)
+		if err != nil {
+			logger.Errorf("Failed to build %s notification: %v", n.Reason, err)
+			return
+		}
+		logger.Infof("Processing %s notification: %s", n.Reason, newEvent.key)
+		queue.Add(newEvent)
+		metrics.QueueDepth.WithLabelValues("event").Set(float64(queue.Len()))
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			// events which happened before k8swatch started are only counted
+			if isInInitialList {
+				if e, ok := obj.(*api_v1.Event); ok && filter.matches(e) {
+					aggregator.seen(e)
+				}
+				return
+			}
+			enqueue("create", obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			enqueue("update", new)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if e, ok := deletedObject(obj).(*api_v1.Event); ok {
+				aggregator.forget(e.UID)
+			}
+		},
+	})
+
+	return &Controller{
+		name:         "event",
+		logger:       logger,
+		clientset:    clientset,
+		queue:        queue,
+		maxRetries:   settings.maxRetries,
+		informer:     informer,
+		gvr:          gvr,
+		cluster:      p.cluster,
+		eventHandler: eventHandler,
+		owners:       p.owners,
+	}
+}
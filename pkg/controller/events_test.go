@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func coreEvent(uid, eventType, reason, kind string, count int32) *api_v1.Event {
+	return &api_v1.Event{
+		ObjectMeta:     meta_v1.ObjectMeta{Namespace: "default", Name: "web.1", UID: types.UID(uid)},
+		InvolvedObject: api_v1.ObjectReference{Kind: kind, Namespace: "default", Name: "web"},
+		Type:           eventType,
+		Reason:         reason,
+		Message:        "Back-off restarting failed container ",
+		Count:          count,
+	}
+}
+
+func TestEventAggregatorObserve(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts []int32
+		want   []bool
+	}{
+		{"first occurrence", []int32{1}, []bool{true}},
+		{"repetitions", []int32{1, 2, 3, 4, 5, 8, 15, 16}, []bool{true, true, false, true, false, true, false, true}},
+		{"unset count", []int32{0, 0, 2}, []bool{true, false, true}},
+		{"first seen repeated", []int32{10, 12, 20}, []bool{true, false, true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := &eventAggregator{notified: map[types.UID]int32{}}
+			for i, count := range test.counts {
+				if got := a.observe(coreEvent("1", "Warning", "BackOff", "Pod", count)); got != test.want[i] {
+					t.Errorf("observe() of count %d = %v, want %v", count, got, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEventAggregatorSeenAndForget(t *testing.T) {
+	a := &eventAggregator{notified: map[types.UID]int32{}}
+	a.seen(coreEvent("1", "Warning", "BackOff", "Pod", 4))
+	if a.observe(coreEvent("1", "Warning", "BackOff", "Pod", 5)) {
+		t.Error("observe() = true for an event listed at startup, want false")
+	}
+	if !a.observe(coreEvent("1", "Warning", "BackOff", "Pod", 8)) {
+		t.Error("observe() = false once the count of a listed event doubled, want true")
+	}
+	if !a.observe(coreEvent("2", "Warning", "BackOff", "Pod", 9)) {
+		t.Error("observe() = false for another event, want true")
+	}
+
+	a.forget("1")
+	if !a.observe(coreEvent("1", "Warning", "BackOff", "Pod", 9)) {
+		t.Error("observe() = false for a forgotten event, want true")
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	e := coreEvent("1", "Warning", "BackOff", "Pod", 3)
+	if got := occurrences(e); got != 3 {
+		t.Errorf("occurrences() = %d, want 3", got)
+	}
+	e.Series = &api_v1.EventSeries{Count: 7}
+	if got := occurrences(e); got != 7 {
+		t.Errorf("occurrences() of a series = %d, want 7", got)
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  config.Events
+		event *api_v1.Event
+		want  bool
+	}{
+		{"warnings by default", config.Events{}, coreEvent("1", "Warning", "BackOff", "Pod", 1), true},
+		{"no normal events by default", config.Events{}, coreEvent("1", "Normal", "Pulled", "Pod", 1), false},
+		{"types", config.Events{Types: []string{"normal"}}, coreEvent("1", "Normal", "Pulled", "Pod", 1), true},
+		{"reason", config.Events{Reasons: []string{"backoff"}}, coreEvent("1", "Warning", "BackOff", "Pod", 1), true},
+		{"other reason", config.Events{Reasons: []string{"FailedMount"}}, coreEvent("1", "Warning", "BackOff", "Pod", 1), false},
+		{"involved kind", config.Events{InvolvedKinds: []string{"Node"}}, coreEvent("1", "Warning", "BackOff", "Pod", 1), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := newEventFilter(test.conf).matches(test.event); got != test.want {
+				t.Errorf("matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEventNotification(t *testing.T) {
+	tests := []struct {
+		name         string
+		event        *api_v1.Event
+		wantSeverity string
+		wantMessage  string
+	}{
+		{"warning", coreEvent("1", "Warning", "BackOff", "Pod", 1), handlers.SeverityWarning, "Pod default/web: Back-off restarting failed container"},
+		{"normal", coreEvent("1", "Normal", "Pulled", "Pod", 1), handlers.SeverityInfo, "Pod default/web: Back-off restarting failed container"},
+		{"repeated", coreEvent("1", "Warning", "BackOff", "Pod", 4), handlers.SeverityWarning, "Pod default/web: Back-off restarting failed container (x4)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := eventNotification(test.event)
+			if n.Reason != test.event.Reason || n.Severity != test.wantSeverity || n.Message != test.wantMessage {
+				t.Errorf("eventNotification() = %s, %s, %q, want %s, %s, %q", n.Reason, n.Severity, n.Message, test.event.Reason, test.wantSeverity, test.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+)
+
+// listScope is what the api server lists and watches for a resource,
+// resources with the same scope share one informer factory
+type listScope struct {
+	namespace     string
+	labelSelector string
+	fieldSelector string
+}
+
+// newListScope returns the server side filtering of the resource configured
+// under key, patterns of the namespace filter that are not globs are applied
+// as namespace or field selector, the others are left to the pipeline
+func newListScope(conf *config.Config, key string, namespaced bool) (listScope, error) {
+	selector := conf.Selectors[key]
+	if _, err := labels.Parse(selector.LabelSelector); err != nil {
+		return listScope{}, fmt.Errorf("invalid label selector of %s: %v", key, err)
+	}
+	if _, err := fields.ParseSelector(selector.FieldSelector); err != nil {
+		return listScope{}, fmt.Errorf("invalid field selector of %s: %v", key, err)
+	}
+
+	scope := listScope{labelSelector: selector.LabelSelector, fieldSelector: selector.FieldSelector}
+	if !namespaced {
+		return scope, nil
+	}
+
+	scope.namespace = conf.Namespace
+	if include := conf.Namespaces.Include; scope.namespace == "" && len(include) == 1 && !isGlob(include[0]) {
+		scope.namespace = include[0]
+	}
+
+	var selectors []string
+	if scope.fieldSelector != "" {
+		selectors = append(selectors, scope.fieldSelector)
+	}
+	for _, ns := range conf.Namespaces.Exclude {
+		if !isGlob(ns) {
+			selectors = append(selectors, "metadata.namespace!="+ns)
+		}
+	}
+	scope.fieldSelector = strings.Join(selectors, ",")
+	return scope, nil
+}
+
+func (s listScope) tweakListOptions(options *meta_v1.ListOptions) {
+	options.LabelSelector = s.labelSelector
+	options.FieldSelector = s.fieldSelector
+}
+
+// factories creates one informer factory per list scope
+type factories struct {
+	clientset     kubernetes.Interface
+	dynamicClient dynamic.Interface
+	typed         map[listScope]informers.SharedInformerFactory
+	dynamic       map[listScope]dynamicinformer.DynamicSharedInformerFactory
+}
+
+func newFactories(clientset kubernetes.Interface, dynamicClient dynamic.Interface) *factories {
+	return &factories{
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		typed:         map[listScope]informers.SharedInformerFactory{},
+		dynamic:       map[listScope]dynamicinformer.DynamicSharedInformerFactory{},
+	}
+}
+
+func (f *factories) forTyped(scope listScope) informers.SharedInformerFactory {
+	factory, ok := f.typed[scope]
+	if !ok {
+		factory = informers.NewSharedInformerFactoryWithOptions(
+			f.clientset,
+			0, //Skip resync
+			informers.WithNamespace(scope.namespace),
+			informers.WithTweakListOptions(scope.tweakListOptions),
+		)
+		f.typed[scope] = factory
+	}
+	return factory
+}
+
+func (f *factories) forDynamic(scope listScope) dynamicinformer.DynamicSharedInformerFactory {
+	factory, ok := f.dynamic[scope]
+	if !ok {
+		factory = dynamicinformer.NewFilteredDynamicSharedInformerFactory(
+			f.dynamicClient,
+			0, //Skip resync
+			scope.namespace,
+			scope.tweakListOptions,
+		)
+		f.dynamic[scope] = factory
+	}
+	return factory
+}
+
+// start starts the informers of every factory
+func (f *factories) start(stopCh <-chan struct{}) {
+	for _, factory := range f.typed {
+		factory.Start(stopCh)
+	}
+	for _, factory := range f.dynamic {
+		factory.Start(stopCh)
+	}
+}
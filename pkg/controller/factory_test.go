@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"testing"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFactoriesPerListScope(t *testing.T) {
+	f := newFactories(fake.NewSimpleClientset(), dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()))
+
+	all := listScope{}
+	prod := listScope{namespace: "prod"}
+	web := listScope{namespace: "prod", labelSelector: "app=web"}
+
+	if f.forTyped(all) != f.forTyped(listScope{}) {
+		t.Error("forTyped() of the same scope returned another factory")
+	}
+	if f.forTyped(prod) == f.forTyped(all) || f.forTyped(web) == f.forTyped(prod) {
+		t.Error("forTyped() of different scopes returned the same factory")
+	}
+	if f.forDynamic(web) != f.forDynamic(web) || f.forDynamic(web) == f.forDynamic(all) {
+		t.Error("forDynamic() does not return one factory per scope")
+	}
+	if len(f.typed) != 3 || len(f.dynamic) != 2 {
+		t.Errorf("factories = %d typed, %d dynamic, want 3 and 2", len(f.typed), len(f.dynamic))
+	}
+}
+
+func TestListScopeTweakListOptions(t *testing.T) {
+	options := meta_v1.ListOptions{LabelSelector: "old", FieldSelector: "old"}
+	listScope{namespace: "prod", labelSelector: "app=web", fieldSelector: "metadata.namespace!=kube-system"}.tweakListOptions(&options)
+	if options.LabelSelector != "app=web" || options.FieldSelector != "metadata.namespace!=kube-system" {
+		t.Errorf("tweakListOptions() = %+v, want the selectors of the scope", options)
+	}
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	defaultLeaseName     = "k8swatch"
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+
+	// serviceAccountNamespace holds the namespace of the pod when running in cluster
+	serviceAccountNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// runLeaderElection runs run while holding the Lease until ctx is done, a replica losing
+// the Lease stops its controllers and campaigns again, the Lease is released when ctx is done
+// so another replica takes over without waiting for it to expire
+func runLeaderElection(ctx context.Context, clientset kubernetes.Interface, conf config.LeaderElection, run func(ctx context.Context)) error {
+	electionConfig, err := newLeaderElectionConfig(clientset, conf, run)
+	if err != nil {
+		return err
+	}
+
+	// a replica waiting for the Lease is ready, it has nothing to sync
+	controllersReady.set(nil)
+
+	logger := logrus.WithField("pkg", "k8swatch-leader")
+	for ctx.Err() == nil {
+		logger.Infof("Campaigning for lease %s as %s", electionConfig.Lock.Describe(), electionConfig.Lock.Identity())
+		elector, err := leaderelection.NewLeaderElector(electionConfig)
+		if err != nil {
+			return err
+		}
+		elector.Run(ctx)
+	}
+	return nil
+}
+
+// newLeaderElectionConfig returns the election over the Lease of conf, the identity
+// of the replica is its host name, i.e. the pod name, made unique by a uuid
+func newLeaderElectionConfig(clientset kubernetes.Interface, conf config.LeaderElection, run func(ctx context.Context)) (leaderelection.LeaderElectionConfig, error) {
+	name := conf.LeaseName
+	if name == "" {
+		name = defaultLeaseName
+	}
+	namespace := conf.LeaseNamespace
+	if namespace == "" {
+		namespace = podNamespace()
+	}
+
+	leaseDuration, err := durationOrDefault(conf.LeaseDuration, defaultLeaseDuration)
+	if err != nil {
+		return leaderelection.LeaderElectionConfig{}, fmt.Errorf("invalid lease duration: %v", err)
+	}
+	renewDeadline, err := durationOrDefault(conf.RenewDeadline, defaultRenewDeadline)
+	if err != nil {
+		return leaderelection.LeaderElectionConfig{}, fmt.Errorf("invalid renew deadline: %v", err)
+	}
+	retryPeriod, err := durationOrDefault(conf.RetryPeriod, defaultRetryPeriod)
+	if err != nil {
+		return leaderelection.LeaderElectionConfig{}, fmt.Errorf("invalid retry period: %v", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "k8swatch"
+	}
+	identity := hostname + "_" + string(uuid.NewUUID())
+
+	logger := logrus.WithField("pkg", "k8swatch-leader")
+	return leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  meta_v1.ObjectMeta{Name: name, Namespace: namespace},
+			Client:     clientset.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				logger.Info("Started leading, starting controllers")
+				run(ctx)
+			},
+			OnStoppedLeading: func() {
+				logger.Info("Stopped leading, controllers stopped")
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					logger.Infof("New leader elected: %s", leader)
+				}
+			},
+		},
+	}, nil
+}
+
+func durationOrDefault(d string, def time.Duration) (time.Duration, error) {
+	if d == "" {
+		return def, nil
+	}
+	return time.ParseDuration(d)
+}
+
+// podNamespace returns the namespace k8swatch runs in, default out of cluster
+func podNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if b, err := ioutil.ReadFile(serviceAccountNamespace); err == nil {
+		if ns := strings.TrimSpace(string(b)); ns != "" {
+			return ns
+		}
+	}
+	return meta_v1.NamespaceDefault
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewLeaderElectionConfig(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "watch")
+
+	tests := []struct {
+		name          string
+		conf          config.LeaderElection
+		wantLock      string
+		wantDurations [3]time.Duration
+		wantErr       bool
+	}{
+		{"defaults", config.LeaderElection{}, "watch/k8swatch", [3]time.Duration{15 * time.Second, 10 * time.Second, 2 * time.Second}, false},
+		{
+			"configured",
+			config.LeaderElection{LeaseName: "watcher", LeaseNamespace: "ops", LeaseDuration: "1m", RenewDeadline: "40s", RetryPeriod: "5s"},
+			"ops/watcher",
+			[3]time.Duration{time.Minute, 40 * time.Second, 5 * time.Second},
+			false,
+		},
+		{"invalid lease duration", config.LeaderElection{LeaseDuration: "15"}, "", [3]time.Duration{}, true},
+		{"invalid renew deadline", config.LeaderElection{RenewDeadline: "soon"}, "", [3]time.Duration{}, true},
+		{"invalid retry period", config.LeaderElection{RetryPeriod: "-"}, "", [3]time.Duration{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := newLeaderElectionConfig(fake.NewSimpleClientset(), test.conf, func(ctx context.Context) {})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("newLeaderElectionConfig() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Lock.Describe(); got != test.wantLock {
+				t.Errorf("lock = %s, want %s", got, test.wantLock)
+			}
+			if got := [3]time.Duration{c.LeaseDuration, c.RenewDeadline, c.RetryPeriod}; got != test.wantDurations {
+				t.Errorf("durations = %v, want %v", got, test.wantDurations)
+			}
+			if !c.ReleaseOnCancel {
+				t.Error("ReleaseOnCancel = false, want true")
+			}
+		})
+	}
+}
+
+func TestPodNamespace(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "watch")
+	if got := podNamespace(); got != "watch" {
+		t.Errorf("podNamespace() = %s, want watch", got)
+	}
+}
+
+// replica runs a leader election, leading is closed once it started leading
+type replica struct {
+	cancel  context.CancelFunc
+	leading chan struct{}
+	done    chan error
+}
+
+func startReplica(clientset kubernetes.Interface) *replica {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &replica{cancel: cancel, leading: make(chan struct{}), done: make(chan error, 1)}
+	conf := config.LeaderElection{LeaseNamespace: "default", LeaseDuration: "2s", RenewDeadline: "1s", RetryPeriod: "100ms"}
+
+	go func() {
+		r.done <- runLeaderElection(ctx, clientset, conf, func(ctx context.Context) {
+			close(r.leading)
+			<-ctx.Done()
+		})
+	}()
+	return r
+}
+
+func (r *replica) stop(t *testing.T) {
+	r.cancel()
+	select {
+	case err := <-r.done:
+		if err != nil {
+			t.Errorf("runLeaderElection() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("runLeaderElection() did not return once its context was done")
+	}
+}
+
+func TestRunLeaderElection(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+
+	first := startReplica(clientset)
+	select {
+	case <-first.leading:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the first replica did not start leading")
+	}
+
+	second := startReplica(clientset)
+	defer second.stop(t)
+	select {
+	case <-second.leading:
+		t.Fatal("the second replica started leading while the first holds the lease")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// the first replica releases the lease, the second takes over before it would expire
+	first.stop(t)
+	select {
+	case <-second.leading:
+	case <-time.After(1500 * time.Millisecond):
+		t.Fatal("the second replica did not take over the released lease")
+	}
+
+	lease, err := clientset.CoordinationV1().Leases("default").Get(context.Background(), defaultLeaseName, meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get lease: %v", err)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+		t.Error("the lease has no holder, want the second replica")
+	}
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/utils"
+)
+
+// namespaceFilter is the client side part of config.Namespaces,
+// objects without a namespace always pass
+type namespaceFilter struct {
+	include []string
+	exclude []string
+}
+
+func newNamespaceFilter(conf config.Namespaces) (namespaceFilter, error) {
+	for _, pattern := range append(append([]string{}, conf.Include...), conf.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return namespaceFilter{}, fmt.Errorf("invalid namespace pattern %q: %v", pattern, err)
+		}
+	}
+	return namespaceFilter{include: conf.Include, exclude: conf.Exclude}, nil
+}
+
+// allows reports whether events in namespace are watched
+func (f namespaceFilter) allows(namespace string) bool {
+	if namespace == "" {
+		return true
+	}
+	if len(f.include) != 0 && !utils.MatchesAny(f.include, namespace) {
+		return false
+	}
+	return !utils.MatchesAny(f.exclude, namespace)
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+)
+
+func TestNamespaceFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		conf      config.Namespaces
+		namespace string
+		want      bool
+	}{
+		{"no filter", config.Namespaces{}, "default", true},
+		{"cluster scoped", config.Namespaces{Include: []string{"prod"}}, "", true},
+		{"included", config.Namespaces{Include: []string{"prod", "staging"}}, "staging", true},
+		{"not included", config.Namespaces{Include: []string{"prod"}}, "default", false},
+		{"included glob", config.Namespaces{Include: []string{"team-*"}}, "team-a", true},
+		{"excluded", config.Namespaces{Exclude: []string{"kube-system"}}, "kube-system", false},
+		{"excluded glob", config.Namespaces{Exclude: []string{"kube-*"}}, "kube-public", false},
+		{"not excluded", config.Namespaces{Exclude: []string{"kube-*"}}, "default", true},
+		{"exclude wins", config.Namespaces{Include: []string{"team-*"}, Exclude: []string{"team-b"}}, "team-b", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := newNamespaceFilter(test.conf)
+			if err != nil {
+				t.Fatalf("newNamespaceFilter() = %v", err)
+			}
+			if got := f.allows(test.namespace); got != test.want {
+				t.Errorf("allows(%q) = %v, want %v", test.namespace, got, test.want)
+			}
+		})
+	}
+
+	if _, err := newNamespaceFilter(config.Namespaces{Exclude: []string{"["}}); err == nil {
+		t.Error("newNamespaceFilter() = nil with an invalid pattern, want an error")
+	}
+}
+
+func TestNewListScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		conf       config.Config
+		key        string
+		namespaced bool
+		want       listScope
+		wantErr    bool
+	}{
+		{"everything", config.Config{}, "po", true, listScope{}, false},
+		{
+			"selectors of the resource",
+			config.Config{Selectors: map[string]config.Selector{"po": {LabelSelector: "app=web", FieldSelector: "status.phase=Running"}}},
+			"po",
+			true,
+			listScope{labelSelector: "app=web", fieldSelector: "status.phase=Running"},
+			false,
+		},
+		{
+			"selectors of another resource",
+			config.Config{Selectors: map[string]config.Selector{"svc": {LabelSelector: "app=web"}}},
+			"po",
+			true,
+			listScope{},
+			false,
+		},
+		{"namespace", config.Config{Namespace: "prod"}, "po", true, listScope{namespace: "prod"}, false},
+		{"single include", config.Config{Namespaces: config.Namespaces{Include: []string{"prod"}}}, "po", true, listScope{namespace: "prod"}, false},
+		{"glob include", config.Config{Namespaces: config.Namespaces{Include: []string{"prod-*"}}}, "po", true, listScope{}, false},
+		{"several includes", config.Config{Namespaces: config.Namespaces{Include: []string{"a", "b"}}}, "po", true, listScope{}, false},
+		{
+			"excludes",
+			config.Config{
+				Namespaces: config.Namespaces{Exclude: []string{"kube-system", "kube-*", "default"}},
+				Selectors:  map[string]config.Selector{"po": {FieldSelector: "spec.nodeName=a"}},
+			},
+			"po",
+			true,
+			listScope{fieldSelector: "spec.nodeName=a,metadata.namespace!=kube-system,metadata.namespace!=default"},
+			false,
+		},
+		{
+			"cluster scoped",
+			config.Config{Namespace: "prod", Namespaces: config.Namespaces{Exclude: []string{"kube-system"}}},
+			"no",
+			false,
+			listScope{},
+			false,
+		},
+		{"invalid label selector", config.Config{Selectors: map[string]config.Selector{"po": {LabelSelector: "app in ("}}}, "po", true, listScope{}, true},
+		{"invalid field selector", config.Config{Selectors: map[string]config.Selector{"po": {FieldSelector: "a"}}}, "po", true, listScope{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := newListScope(&test.conf, test.key, test.namespaced)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("newListScope() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("newListScope() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
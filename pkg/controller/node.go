@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// nodeConditions are the conditions whose transitions are reported
+var nodeConditions = []api_v1.NodeConditionType{
+	api_v1.NodeReady,
+	api_v1.NodeMemoryPressure,
+	api_v1.NodeDiskPressure,
+	api_v1.NodePIDPressure,
+}
+
+// nodeStates remembers when the states without a transition
+// timestamp of their own, i.e. cordon and taints, last changed
+type nodeStates struct {
+	mu    sync.Mutex
+	since map[string]time.Time
+}
+
+// changed records a change of the state key at now and returns how long
+// the previous state lasted, the bool is false when it was already there
+// before k8swatch started and the duration is a lower bound
+func (s *nodeStates) changed(key string, now time.Time) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	since, ok := s.since[key]
+	if !ok {
+		since = serverStartTime
+	}
+	s.since[key] = now
+	return now.Sub(since), ok
+}
+
+// forget drops the states of a node which left the cluster
+func (s *nodeStates) forget(node string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.since {
+		if strings.HasPrefix(key, node+"/") {
+			delete(s.since, key)
+		}
+	}
+}
+
+// newNodeAnalyzer returns an analyzer reporting node join and leave, transitions of
+// the node conditions, cordon, uncordon and taint changes, each with the duration of the previous state
+func newNodeAnalyzer() analyzer {
+	states := &nodeStates{since: map[string]time.Time{}}
+
+	return func(old, new interface{}) []handlers.Notification {
+		oldNode, _ := old.(*api_v1.Node)
+		newNode, _ := new.(*api_v1.Node)
+		now := time.Now()
+
+		switch {
+		case oldNode == nil && newNode == nil:
+			return nil
+		case oldNode == nil:
+			return []handlers.Notification{{
+				Reason:   "NodeJoined",
+				Message:  fmt.Sprintf("node %s joined the cluster", newNode.Name),
+				Severity: handlers.SeverityInfo,
+				Object:   newNode,
+			}}
+		case newNode == nil:
+			states.forget(oldNode.Name)
+			return []handlers.Notification{{
+				Reason:   "NodeLeft",
+				Message:  fmt.Sprintf("node %s left the cluster after %s", oldNode.Name, formatDuration(now.Sub(oldNode.CreationTimestamp.Time), true)),
+				Severity: handlers.SeverityWarning,
+				Object:   oldNode,
+			}}
+		}
+
+		var notifications []handlers.Notification
+		notify := func(reason, severity, format string, args ...interface{}) {
+			notifications = append(notifications, handlers.Notification{
+				Reason:   reason,
+				Message:  fmt.Sprintf(format, args...),
+				Severity: severity,
+				Object:   newNode,
+			})
+		}
+
+		for _, conditionType := range nodeConditions {
+			oldCondition := findNodeCondition(oldNode, conditionType)
+			condition := findNodeCondition(newNode, conditionType)
+			if condition == nil || oldCondition == nil || condition.Status == oldCondition.Status {
+				continue
+			}
+			lasted := condition.LastTransitionTime.Sub(oldCondition.LastTransitionTime.Time)
+			if condition.LastTransitionTime.IsZero() {
+				lasted = now.Sub(oldCondition.LastTransitionTime.Time)
+			}
+			notify(string(conditionType), conditionSeverity(conditionType, condition.Status),
+				"node %s %s %s -> %s after %s: %s", newNode.Name, conditionType, oldCondition.Status, condition.Status,
+				formatDuration(lasted, true), condition.Message)
+		}
+
+		if newNode.Spec.Unschedulable != oldNode.Spec.Unschedulable {
+			lasted, exact := states.changed(newNode.Name+"/unschedulable", now)
+			if newNode.Spec.Unschedulable {
+				notify("NodeCordoned", handlers.SeverityWarning, "node %s cordoned, was schedulable for %s", newNode.Name, formatDuration(lasted, exact))
+			} else {
+				notify("NodeUncordoned", handlers.SeverityInfo, "node %s uncordoned, was cordoned for %s", newNode.Name, formatDuration(lasted, exact))
+			}
+		}
+
+		for _, taint := range newNode.Spec.Taints {
+			if !hasTaint(oldNode.Spec.Taints, taint) {
+				lasted, exact := states.changed(newNode.Name+"/taint/"+taintKey(taint), now)
+				notify("TaintAdded", handlers.SeverityInfo, "node %s tainted %s, was untainted for %s", newNode.Name, taintKey(taint), formatDuration(lasted, exact))
+			}
+		}
+		for _, taint := range oldNode.Spec.Taints {
+			if !hasTaint(newNode.Spec.Taints, taint) {
+				lasted, exact := states.changed(newNode.Name+"/taint/"+taintKey(taint), now)
+				if !exact && taint.TimeAdded != nil {
+					lasted, exact = now.Sub(taint.TimeAdded.Time), true
+				}
+				notify("TaintRemoved", handlers.SeverityInfo, "node %s untainted %s, was tainted for %s", newNode.Name, taintKey(taint), formatDuration(lasted, exact))
+			}
+		}
+
+		return notifications
+	}
+}
+
+func findNodeCondition(node *api_v1.Node, conditionType api_v1.NodeConditionType) *api_v1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == conditionType {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// conditionSeverity is critical for a node which is not ready,
+// Ready is the only condition which is healthy when true
+func conditionSeverity(conditionType api_v1.NodeConditionType, status api_v1.ConditionStatus) string {
+	healthy := status == api_v1.ConditionFalse
+	if conditionType == api_v1.NodeReady {
+		healthy = status == api_v1.ConditionTrue
+	}
+	switch {
+	case healthy:
+		return handlers.SeverityInfo
+	case conditionType == api_v1.NodeReady:
+		return handlers.SeverityCritical
+	default:
+		return handlers.SeverityWarning
+	}
+}
+
+func taintKey(taint api_v1.Taint) string {
+	if taint.Value == "" {
+		return taint.Key + ":" + string(taint.Effect)
+	}
+	return taint.Key + "=" + taint.Value + ":" + string(taint.Effect)
+}
+
+func hasTaint(taints []api_v1.Taint, taint api_v1.Taint) bool {
+	for _, t := range taints {
+		if t.Key == taint.Key && t.Value == taint.Value && t.Effect == taint.Effect {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDuration rounds d to the second, a duration which is not exact is a lower bound
+func formatDuration(d time.Duration, exact bool) string {
+	d = d.Round(time.Second)
+	if exact {
+		return d.String()
+	}
+	return "more than " + d.String()
+}
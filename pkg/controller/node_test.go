@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var transitioned = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// nodeWith returns the node a with the conditions, change tweaks it from the defaults
+func nodeWith(change func(n *api_v1.Node), conditions ...api_v1.NodeCondition) *api_v1.Node {
+	n := &api_v1.Node{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "a"},
+		Status:     api_v1.NodeStatus{Conditions: conditions},
+	}
+	if change != nil {
+		change(n)
+	}
+	return n
+}
+
+func condition(conditionType api_v1.NodeConditionType, status api_v1.ConditionStatus, after time.Duration) api_v1.NodeCondition {
+	return api_v1.NodeCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastTransitionTime: meta_v1.NewTime(transitioned.Add(after)),
+		Message:            "kubelet stopped posting node status",
+	}
+}
+
+func cordoned(n *api_v1.Node) { n.Spec.Unschedulable = true }
+
+func tainted(n *api_v1.Node) {
+	n.Spec.Taints = []api_v1.Taint{{Key: "dedicated", Value: "gpu", Effect: api_v1.TaintEffectNoSchedule}}
+}
+
+// reasons returns reason|severity of every notification
+func reasons(notifications []handlers.Notification) []string {
+	var got []string
+	for _, n := range notifications {
+		got = append(got, n.Reason+"|"+n.Severity)
+	}
+	return got
+}
+
+func TestNodeAnalyzer(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new interface{}
+		want     []string
+	}{
+		{"joined", nil, nodeWith(nil), []string{"NodeJoined|info"}},
+		{"left", nodeWith(nil), nil, []string{"NodeLeft|warning"}},
+		{"not a node", &api_v1.Pod{}, &api_v1.Pod{}, nil},
+		{
+			"unchanged",
+			nodeWith(nil, condition(api_v1.NodeReady, api_v1.ConditionTrue, 0)),
+			nodeWith(nil, condition(api_v1.NodeReady, api_v1.ConditionTrue, 0)),
+			nil,
+		},
+		{
+			"not ready",
+			nodeWith(nil, condition(api_v1.NodeReady, api_v1.ConditionTrue, 0)),
+			nodeWith(nil, condition(api_v1.NodeReady, api_v1.ConditionUnknown, time.Hour)),
+			[]string{"Ready|critical"},
+		},
+		{
+			"ready again",
+			nodeWith(nil, condition(api_v1.NodeReady, api_v1.ConditionFalse, 0)),
+			nodeWith(nil, condition(api_v1.NodeReady, api_v1.ConditionTrue, time.Minute)),
+			[]string{"Ready|info"},
+		},
+		{
+			"memory pressure",
+			nodeWith(nil, condition(api_v1.NodeMemoryPressure, api_v1.ConditionFalse, 0)),
+			nodeWith(nil, condition(api_v1.NodeMemoryPressure, api_v1.ConditionTrue, time.Minute)),
+			[]string{"MemoryPressure|warning"},
+		},
+		{
+			"new condition",
+			nodeWith(nil),
+			nodeWith(nil, condition(api_v1.NodeDiskPressure, api_v1.ConditionTrue, 0)),
+			nil,
+		},
+		{"cordoned", nodeWith(nil), nodeWith(cordoned), []string{"NodeCordoned|warning"}},
+		{"uncordoned", nodeWith(cordoned), nodeWith(nil), []string{"NodeUncordoned|info"}},
+		{"taint added", nodeWith(nil), nodeWith(tainted), []string{"TaintAdded|info"}},
+		{"taint removed", nodeWith(tainted), nodeWith(nil), []string{"TaintRemoved|info"}},
+		{
+			"several changes",
+			nodeWith(nil, condition(api_v1.NodeReady, api_v1.ConditionTrue, 0)),
+			nodeWith(func(n *api_v1.Node) { cordoned(n); tainted(n) }, condition(api_v1.NodeReady, api_v1.ConditionFalse, time.Hour)),
+			[]string{"Ready|critical", "NodeCordoned|warning", "TaintAdded|info"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := reasons(newNodeAnalyzer()(test.old, test.new))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("newNodeAnalyzer() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNodeAnalyzerDurations(t *testing.T) {
+	analyze := newNodeAnalyzer()
+
+	notifications := analyze(
+		nodeWith(nil, condition(api_v1.NodeReady, api_v1.ConditionTrue, 0)),
+		nodeWith(nil, condition(api_v1.NodeReady, api_v1.ConditionFalse, 90*time.Minute)),
+	)
+	want := "node a Ready True -> False after 1h30m0s: kubelet stopped posting node status"
+	if len(notifications) != 1 || notifications[0].Message != want {
+		t.Errorf("condition transition = %q, want %q", summarize(notifications), want)
+	}
+
+	// the node was schedulable before k8swatch started, that lasted at least its uptime
+	notifications = analyze(nodeWith(nil), nodeWith(cordoned))
+	if len(notifications) != 1 || !strings.HasPrefix(notifications[0].Message, "node a cordoned, was schedulable for more than ") {
+		t.Errorf("cordon = %q, want a lower bound", summarize(notifications))
+	}
+
+	notifications = analyze(nodeWith(cordoned), nodeWith(nil))
+	want = "node a uncordoned, was cordoned for 0s"
+	if len(notifications) != 1 || notifications[0].Message != want {
+		t.Errorf("uncordon = %q, want %q", summarize(notifications), want)
+	}
+
+	removed := nodeWith(func(n *api_v1.Node) {
+		tainted(n)
+		added := meta_v1.NewTime(time.Now().Add(-time.Hour))
+		n.Spec.Taints[0].TimeAdded = &added
+	})
+	notifications = analyze(removed, nodeWith(nil))
+	want = "node a untainted dedicated=gpu:NoSchedule, was tainted for 1h0m0s"
+	if len(notifications) != 1 || notifications[0].Message != want {
+		t.Errorf("taint removal = %q, want %q", summarize(notifications), want)
+	}
+
+	// a node leaving the cluster forgets its states
+	analyze(nodeWith(nil), nil)
+	notifications = analyze(nodeWith(cordoned), nodeWith(nil))
+	if len(notifications) != 1 || !strings.Contains(notifications[0].Message, "more than") {
+		t.Errorf("uncordon after the node left = %q, want a lower bound", summarize(notifications))
+	}
+}
+
+func TestConditionSeverity(t *testing.T) {
+	tests := []struct {
+		conditionType api_v1.NodeConditionType
+		status        api_v1.ConditionStatus
+		want          string
+	}{
+		{api_v1.NodeReady, api_v1.ConditionTrue, handlers.SeverityInfo},
+		{api_v1.NodeReady, api_v1.ConditionFalse, handlers.SeverityCritical},
+		{api_v1.NodeReady, api_v1.ConditionUnknown, handlers.SeverityCritical},
+		{api_v1.NodeDiskPressure, api_v1.ConditionFalse, handlers.SeverityInfo},
+		{api_v1.NodeDiskPressure, api_v1.ConditionTrue, handlers.SeverityWarning},
+		{api_v1.NodePIDPressure, api_v1.ConditionUnknown, handlers.SeverityWarning},
+	}
+	for _, test := range tests {
+		if got := conditionSeverity(test.conditionType, test.status); got != test.want {
+			t.Errorf("conditionSeverity(%s, %s) = %s, want %s", test.conditionType, test.status, got, test.want)
+		}
+	}
+}
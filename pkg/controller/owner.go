@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+	"github.com/walk1ng/k8swatch/pkg/utils"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// maxOwnerDepth bounds the walk up the controller references
+const maxOwnerDepth = 5
+
+// ownerResolver walks the controller references of objects up to their top level
+// controller, e.g. pod, ReplicaSet, Deployment, through the shared informer caches
+type ownerResolver struct {
+	// getters look up the owners which may be controlled themselves,
+	// other owners such as Deployments or StatefulSets are top level
+	getters map[schema.GroupKind]func(namespace, name string) (meta_v1.Object, error)
+	// synced report whether the caches behind the getters synced
+	synced []cache.InformerSynced
+}
+
+// newOwnerResolver creates a resolver backed by the informers of f, pods are only
+// cached to resolve the objects involved in core/v1 Events
+func newOwnerResolver(f informers.SharedInformerFactory, pods bool) *ownerResolver {
+	replicaSets := f.Apps().V1().ReplicaSets()
+	jobs := f.Batch().V1().Jobs()
+
+	r := &ownerResolver{
+		getters: map[schema.GroupKind]func(namespace, name string) (meta_v1.Object, error){
+			{Group: "apps", Kind: "ReplicaSet"}: func(namespace, name string) (meta_v1.Object, error) {
+				return replicaSets.Lister().ReplicaSets(namespace).Get(name)
+			},
+			{Group: "batch", Kind: "Job"}: func(namespace, name string) (meta_v1.Object, error) {
+				return jobs.Lister().Jobs(namespace).Get(name)
+			},
+		},
+		synced: []cache.InformerSynced{replicaSets.Informer().HasSynced, jobs.Informer().HasSynced},
+	}
+	if pods {
+		p := f.Core().V1().Pods()
+		r.getters[schema.GroupKind{Kind: "Pod"}] = func(namespace, name string) (meta_v1.Object, error) {
+			return p.Lister().Pods(namespace).Get(name)
+		}
+		r.synced = append(r.synced, p.Informer().HasSynced)
+	}
+	return r
+}
+
+// hasSynced reports whether the caches of the resolver synced
+func (r *ownerResolver) hasSynced() bool {
+	if r == nil {
+		return true
+	}
+	for _, synced := range r.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveReference returns the top level controller of the object ref points to,
+// nil if the object is not cached, e.g. the involved object of an Event
+func (r *ownerResolver) resolveReference(ref api_v1.ObjectReference) *handlers.Owner {
+	if r == nil {
+		return nil
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil
+	}
+	get, ok := r.getters[schema.GroupKind{Group: gv.Group, Kind: ref.Kind}]
+	if !ok {
+		return nil
+	}
+	obj, err := get(ref.Namespace, ref.Name)
+	if err != nil {
+		return nil
+	}
+	return r.resolve(obj)
+}
+
+// resolve returns the top level controller of obj, nil if obj has no controller
+// or owners are not resolved, an owner missing from the caches ends the walk
+func (r *ownerResolver) resolve(obj interface{}) *handlers.Owner {
+	if r == nil {
+		return nil
+	}
+
+	objMeta := utils.GetObjectMetaData(obj)
+	ref := meta_v1.GetControllerOfNoCopy(&objMeta)
+	if ref == nil {
+		return nil
+	}
+
+	for depth := 0; depth < maxOwnerDepth; depth++ {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			break
+		}
+		get, ok := r.getters[schema.GroupKind{Group: gv.Group, Kind: ref.Kind}]
+		if !ok {
+			break
+		}
+		owner, err := get(objMeta.Namespace, ref.Name)
+		if err != nil {
+			break
+		}
+		next := meta_v1.GetControllerOfNoCopy(owner)
+		if next == nil {
+			break
+		}
+		ref = next
+	}
+
+	// owners live in the namespace of the objects they control
+	return &handlers.Owner{Kind: ref.Kind, Namespace: objMeta.Namespace, Name: ref.Name}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// controlledBy returns object metadata controlled by the owner apiVersion, kind and name
+func controlledBy(name, apiVersion, kind, owner string) meta_v1.ObjectMeta {
+	controller := true
+	objMeta := meta_v1.ObjectMeta{Namespace: "default", Name: name}
+	if owner != "" {
+		objMeta.OwnerReferences = []meta_v1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: owner, Controller: &controller}}
+	}
+	return objMeta
+}
+
+// startOwnerResolver returns a resolver whose caches synced the objects
+func startOwnerResolver(t *testing.T, pods bool, objects ...runtime.Object) *ownerResolver {
+	f := informers.NewSharedInformerFactory(fake.NewSimpleClientset(objects...), 0)
+	r := newOwnerResolver(f, pods)
+
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	f.Start(stop)
+	if !cache.WaitForCacheSync(stop, r.hasSynced) {
+		t.Fatal("owner caches did not sync")
+	}
+	return r
+}
+
+func TestOwnerResolverResolve(t *testing.T) {
+	r := startOwnerResolver(t, false,
+		&apps_v1.ReplicaSet{ObjectMeta: controlledBy("web-1", "apps/v1", "Deployment", "web")},
+		&apps_v1.ReplicaSet{ObjectMeta: controlledBy("orphan", "", "", "")},
+		&batch_v1.Job{ObjectMeta: controlledBy("backup-1", "batch/v1", "CronJob", "backup")},
+	)
+
+	tests := []struct {
+		name string
+		obj  interface{}
+		want *handlers.Owner
+	}{
+		{"no controller", &api_v1.Pod{ObjectMeta: controlledBy("a", "", "", "")}, nil},
+		{"deployment", &api_v1.Pod{ObjectMeta: controlledBy("a", "apps/v1", "ReplicaSet", "web-1")}, &handlers.Owner{Kind: "Deployment", Namespace: "default", Name: "web"}},
+		{"cronjob", &api_v1.Pod{ObjectMeta: controlledBy("a", "batch/v1", "Job", "backup-1")}, &handlers.Owner{Kind: "CronJob", Namespace: "default", Name: "backup"}},
+		{"orphaned replicaset", &api_v1.Pod{ObjectMeta: controlledBy("a", "apps/v1", "ReplicaSet", "orphan")}, &handlers.Owner{Kind: "ReplicaSet", Namespace: "default", Name: "orphan"}},
+		{"owner not cached", &api_v1.Pod{ObjectMeta: controlledBy("a", "apps/v1", "ReplicaSet", "gone")}, &handlers.Owner{Kind: "ReplicaSet", Namespace: "default", Name: "gone"}},
+		{"top level owner", &api_v1.Pod{ObjectMeta: controlledBy("a", "apps/v1", "StatefulSet", "db")}, &handlers.Owner{Kind: "StatefulSet", Namespace: "default", Name: "db"}},
+		{"replicaset", &apps_v1.ReplicaSet{ObjectMeta: controlledBy("web-1", "apps/v1", "Deployment", "web")}, &handlers.Owner{Kind: "Deployment", Namespace: "default", Name: "web"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := r.resolve(test.obj); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("resolve() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestOwnerResolverResolveReference(t *testing.T) {
+	objects := []runtime.Object{
+		&apps_v1.ReplicaSet{ObjectMeta: controlledBy("web-1", "apps/v1", "Deployment", "web")},
+		&api_v1.Pod{ObjectMeta: controlledBy("web-1-a", "apps/v1", "ReplicaSet", "web-1")},
+	}
+	pod := api_v1.ObjectReference{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "web-1-a"}
+	web := &handlers.Owner{Kind: "Deployment", Namespace: "default", Name: "web"}
+
+	tests := []struct {
+		name string
+		pods bool
+		ref  api_v1.ObjectReference
+		want *handlers.Owner
+	}{
+		{"pod", true, pod, web},
+		{"pods not cached", false, pod, nil},
+		{"replicaset", true, api_v1.ObjectReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Namespace: "default", Name: "web-1"}, web},
+		{"missing pod", true, api_v1.ObjectReference{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "gone"}, nil},
+		{"not resolved kind", true, api_v1.ObjectReference{APIVersion: "v1", Kind: "Node", Name: "a"}, nil},
+		{"invalid api version", true, api_v1.ObjectReference{APIVersion: "a/b/c", Kind: "Pod", Namespace: "default", Name: "web-1-a"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := startOwnerResolver(t, test.pods, objects...)
+			if got := r.resolveReference(test.ref); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("resolveReference() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestOwnerResolverHasSynced(t *testing.T) {
+	var disabled *ownerResolver
+	if !disabled.hasSynced() || disabled.resolve(&api_v1.Pod{}) != nil || disabled.resolveReference(api_v1.ObjectReference{}) != nil {
+		t.Error("a nil resolver must be synced and resolve no owner")
+	}
+
+	f := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	r := newOwnerResolver(f, true)
+	if r.hasSynced() {
+		t.Error("hasSynced() = true before the informers started, want false")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	f.Start(stop)
+	deadline := time.Now().Add(5 * time.Second)
+	for !r.hasSynced() {
+		if time.Now().After(deadline) {
+			t.Fatal("hasSynced() = false after the informers started, want true")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/diff"
+	"github.com/walk1ng/k8swatch/pkg/filter"
+	"github.com/walk1ng/k8swatch/pkg/utils"
+)
+
+// pipeline holds the stages an informer callback goes through
+// before its event is queued, it is shared by every controller
+type pipeline struct {
+	logger     *logrus.Entry
+	namespaces namespaceFilter
+	differ     *diff.Differ
+	updates    []updateFilter
+	filters    []*filter.Filter
+	// owners is nil unless owner enrichment is enabled
+	owners *ownerResolver
+	// cluster names the watched cluster in the events handed to the handlers
+	cluster string
+}
+
+func newPipeline(conf *config.Config, factories *factories) (*pipeline, error) {
+	namespaces, err := newNamespaceFilter(conf.Namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	updates, err := newUpdateFilters(conf.Updates)
+	if err != nil {
+		return nil, err
+	}
+
+	var filters []*filter.Filter
+	for i, f := range conf.Filters {
+		compiled, err := filter.New(f.Name, f.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %d (%s): %v", i, f.Name, err)
+		}
+		filters = append(filters, compiled)
+	}
+
+	var owners *ownerResolver
+	if conf.Enrichment.Owners {
+		// the caches of the owners are only restricted to the watched namespaces
+		scope, err := newListScope(conf, "", true)
+		if err != nil {
+			return nil, err
+		}
+		owners = newOwnerResolver(factories.forTyped(scope), conf.Resource.Event)
+	}
+
+	return &pipeline{
+		logger:     logrus.WithField("pkg", "k8swatch-pipeline"),
+		namespaces: namespaces,
+		differ:     diff.New(conf.Diff.IgnorePaths),
+		updates:    updates,
+		filters:    filters,
+		owners:     owners,
+		cluster:    conf.Cluster,
+	}, nil
+}
+
+// process runs an informer callback through the stages, the returned string
+// tells why the callback is dropped, the event is only valid when it is empty
+func (p *pipeline) process(eventType, resourceType string, obj, oldObj interface{}) (Event, string, error) {
+	if namespace := utils.GetObjectMetaData(deletedObject(obj)).Namespace; !p.namespaces.allows(namespace) {
+		return Event{}, "namespace " + namespace + " is filtered", nil
+	}
+
+	var changes *diff.Diff
+	if eventType == "update" {
+		if ok, reason := significant(p.updates, oldObj, obj); !ok {
+			return Event{}, reason, nil
+		}
+		var err error
+		changes, err = p.differ.Compute(oldObj, obj)
+		if err != nil {
+			p.logger.Errorf("Failed to compute changes of %s: %v", resourceType, err)
+		} else if changes.Empty() {
+			return Event{}, "only fields ignored by the diff changed", nil
+		}
+	}
+
+	if !p.matches(eventType, deletedObject(obj), oldObj) {
+		return Event{}, "no filter matches", nil
+	}
+
+	e, err := newEvent(eventType, resourceType, obj, oldObj, changes, p.owners.resolve(deletedObject(obj)))
+	return e, "", err
+}
+
+// matches reports whether any filter lets the event through,
+// a filter failing to evaluate, e.g. on a missing field, does not match
+func (p *pipeline) matches(eventType string, obj, oldObj interface{}) bool {
+	if len(p.filters) == 0 {
+		return true
+	}
+
+	kind := utils.GetObjectKind(obj)
+	for _, f := range p.filters {
+		matched, err := f.Matches(eventType, kind, obj, oldObj)
+		if err != nil {
+			p.logger.Debugf("Filter %s does not apply to %s: %v", f.Name(), kind, err)
+			continue
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPipelineProcessUpdate(t *testing.T) {
+	old := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "web", ResourceVersion: "1"}}
+	status := old.DeepCopy()
+	status.ResourceVersion = "2"
+	status.Status.Phase = api_v1.PodRunning
+	labeled := old.DeepCopy()
+	labeled.ResourceVersion = "2"
+	labeled.Labels = map[string]string{"app": "web"}
+
+	tests := []struct {
+		name       string
+		conf       config.Config
+		new        *api_v1.Pod
+		wantReason string
+	}{
+		{"status only", config.Config{}, status, "only fields ignored by the diff changed"},
+		{"labels", config.Config{}, labeled, ""},
+		{"status in the diff", config.Config{Diff: config.Diff{IgnorePaths: []string{"metadata.resourceVersion"}}}, status, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := newPipeline(&test.conf, newFactories(fake.NewSimpleClientset(), nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, reason, err := p.process("update", "pod", test.new, old)
+			if err != nil {
+				t.Fatalf("process() = %v", err)
+			}
+			if reason != test.wantReason {
+				t.Fatalf("process() dropped with %q, want %q", reason, test.wantReason)
+			}
+			if reason == "" && e.diff.Empty() {
+				t.Error("process() event has an empty diff")
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// imagePullReasons are the waiting reasons of a container whose image cannot be pulled
+var imagePullReasons = map[string]bool{
+	"ImagePullBackOff": true,
+	"ErrImagePull":     true,
+	"InvalidImageName": true,
+}
+
+// analyzePod reports the failures a pod entered between old and new:
+// OOMKilled containers, CrashLoopBackOff, image pull failures and eviction
+func analyzePod(old, new interface{}) []handlers.Notification {
+	oldPod, ok := old.(*api_v1.Pod)
+	if !ok {
+		return nil
+	}
+	newPod, ok := new.(*api_v1.Pod)
+	if !ok {
+		return nil
+	}
+
+	var notifications []handlers.Notification
+	notify := func(reason, severity, format string, args ...interface{}) {
+		notifications = append(notifications, handlers.Notification{
+			Reason:   reason,
+			Message:  fmt.Sprintf(format, args...),
+			Severity: severity,
+			Object:   newPod,
+		})
+	}
+
+	if newPod.Status.Reason == "Evicted" && oldPod.Status.Reason != "Evicted" {
+		notify("Evicted", handlers.SeverityWarning, "Evicted: %s", newPod.Status.Message)
+	}
+
+	statuses := append(append([]api_v1.ContainerStatus{}, newPod.Status.InitContainerStatuses...), newPod.Status.ContainerStatuses...)
+	oldStatuses := append(append([]api_v1.ContainerStatus{}, oldPod.Status.InitContainerStatuses...), oldPod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		oldStatus := findContainerStatus(oldStatuses, status.Name)
+
+		if terminated := lastTermination(status); terminated != nil && terminated.Reason == "OOMKilled" &&
+			!sameTermination(terminated, lastTermination(oldStatus)) {
+			notify("OOMKilled", handlers.SeverityCritical, "container %s OOMKilled (exit %d)", status.Name, terminated.ExitCode)
+		}
+
+		waiting := status.State.Waiting
+		if waiting == nil || waiting.Reason == waitingReason(oldStatus) {
+			continue
+		}
+		switch {
+		case waiting.Reason == "CrashLoopBackOff":
+			notify("CrashLoopBackOff", handlers.SeverityCritical, "container %s CrashLoopBackOff after %d restarts", status.Name, status.RestartCount)
+		case imagePullReasons[waiting.Reason] && !imagePullReasons[waitingReason(oldStatus)]:
+			notify("ImagePullBackOff", handlers.SeverityWarning, "ImagePullBackOff: container %s: %s", status.Name, waiting.Message)
+		}
+	}
+
+	return notifications
+}
+
+func findContainerStatus(statuses []api_v1.ContainerStatus, name string) api_v1.ContainerStatus {
+	for _, status := range statuses {
+		if status.Name == name {
+			return status
+		}
+	}
+	return api_v1.ContainerStatus{}
+}
+
+// lastTermination returns the current termination of a container,
+// or the previous one once the container was restarted
+func lastTermination(status api_v1.ContainerStatus) *api_v1.ContainerStateTerminated {
+	if status.State.Terminated != nil {
+		return status.State.Terminated
+	}
+	return status.LastTerminationState.Terminated
+}
+
+// sameTermination reports whether a and b are the same container termination
+func sameTermination(a, b *api_v1.ContainerStateTerminated) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ContainerID == b.ContainerID && a.FinishedAt.Equal(&b.FinishedAt) && a.Reason == b.Reason
+}
+
+func waitingReason(status api_v1.ContainerStatus) string {
+	if status.State.Waiting == nil {
+		return ""
+	}
+	return status.State.Waiting.Reason
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// podWith returns a pod with the container statuses and the status reason
+func podWith(reason string, statuses ...api_v1.ContainerStatus) *api_v1.Pod {
+	return &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "web"},
+		Status:     api_v1.PodStatus{Reason: reason, Message: "The node was low on resource: memory.", ContainerStatuses: statuses},
+	}
+}
+
+func running(name string) api_v1.ContainerStatus {
+	return api_v1.ContainerStatus{Name: name, State: api_v1.ContainerState{Running: &api_v1.ContainerStateRunning{}}}
+}
+
+func waiting(name, reason string, restarts int32) api_v1.ContainerStatus {
+	return api_v1.ContainerStatus{
+		Name:         name,
+		RestartCount: restarts,
+		State:        api_v1.ContainerState{Waiting: &api_v1.ContainerStateWaiting{Reason: reason, Message: "pull access denied"}},
+	}
+}
+
+func oomKilled(name, containerID string, finishedAt time.Time) api_v1.ContainerStatus {
+	return api_v1.ContainerStatus{
+		Name:  name,
+		State: api_v1.ContainerState{Running: &api_v1.ContainerStateRunning{}},
+		LastTerminationState: api_v1.ContainerState{Terminated: &api_v1.ContainerStateTerminated{
+			Reason:      "OOMKilled",
+			ExitCode:    137,
+			ContainerID: containerID,
+			FinishedAt:  meta_v1.NewTime(finishedAt),
+		}},
+	}
+}
+
+func TestAnalyzePod(t *testing.T) {
+	finished := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		old, new interface{}
+		want     []string
+	}{
+		{"created", nil, podWith("", running("web")), nil},
+		{"not a pod", &api_v1.Node{}, &api_v1.Node{}, nil},
+		{"healthy", podWith("", running("web")), podWith("", running("web")), nil},
+		{"evicted", podWith(""), podWith("Evicted"), []string{"Evicted|warning|Evicted: The node was low on resource: memory."}},
+		{"still evicted", podWith("Evicted"), podWith("Evicted"), nil},
+		{
+			"oom killed",
+			podWith("", running("web")),
+			podWith("", oomKilled("web", "containerd://1", finished)),
+			[]string{"OOMKilled|critical|container web OOMKilled (exit 137)"},
+		},
+		{
+			"same oom kill",
+			podWith("", oomKilled("web", "containerd://1", finished)),
+			podWith("", oomKilled("web", "containerd://1", finished)),
+			nil,
+		},
+		{
+			"another oom kill",
+			podWith("", oomKilled("web", "containerd://1", finished)),
+			podWith("", oomKilled("web", "containerd://2", finished.Add(time.Minute))),
+			[]string{"OOMKilled|critical|container web OOMKilled (exit 137)"},
+		},
+		{
+			"crash loop",
+			podWith("", running("web")),
+			podWith("", waiting("web", "CrashLoopBackOff", 4)),
+			[]string{"CrashLoopBackOff|critical|container web CrashLoopBackOff after 4 restarts"},
+		},
+		{
+			"still crash looping",
+			podWith("", waiting("web", "CrashLoopBackOff", 4)),
+			podWith("", waiting("web", "CrashLoopBackOff", 5)),
+			nil,
+		},
+		{
+			"image pull",
+			podWith("", waiting("web", "ContainerCreating", 0)),
+			podWith("", waiting("web", "ErrImagePull", 0)),
+			[]string{"ImagePullBackOff|warning|ImagePullBackOff: container web: pull access denied"},
+		},
+		{
+			"image pull back off after the error",
+			podWith("", waiting("web", "ErrImagePull", 0)),
+			podWith("", waiting("web", "ImagePullBackOff", 0)),
+			nil,
+		},
+		{
+			"init container",
+			&api_v1.Pod{},
+			&api_v1.Pod{Status: api_v1.PodStatus{InitContainerStatuses: []api_v1.ContainerStatus{waiting("init", "CrashLoopBackOff", 2)}}},
+			[]string{"CrashLoopBackOff|critical|container init CrashLoopBackOff after 2 restarts"},
+		},
+		{
+			"several containers",
+			podWith("", running("web"), running("sidecar")),
+			podWith("", waiting("web", "CrashLoopBackOff", 1), oomKilled("sidecar", "containerd://3", finished)),
+			[]string{
+				"CrashLoopBackOff|critical|container web CrashLoopBackOff after 1 restarts",
+				"OOMKilled|critical|container sidecar OOMKilled (exit 137)",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := summarize(analyzePod(test.old, test.new))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("analyzePod() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// summarize returns reason|severity|message of every notification
+func summarize(notifications []handlers.Notification) []string {
+	var summary []string
+	for _, n := range notifications {
+		summary = append(summary, n.Reason+"|"+n.Severity+"|"+n.Message)
+	}
+	return summary
+}
@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// defaults of config.Queue, the rate of workqueue.DefaultControllerRateLimiter,
+// the backoff of deliveries is that of remote endpoints, 500ms doubling up to 30s
+const (
+	defaultWorkers    = 1
+	defaultQPS        = 10
+	defaultBurst      = 100
+	defaultBaseDelay  = 500 * time.Millisecond
+	defaultMaxDelay   = 30 * time.Second
+	defaultMaxRetries = 5
+)
+
+// queueSettings is the resolved config.Queue of a controller
+type queueSettings struct {
+	workers    int
+	qps        float64
+	burst      int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	maxRetries int
+}
+
+// newQueueSettings returns the defaults overridden by the queue of
+// the config then by the queue of key, fields left to zero are not overridden
+func newQueueSettings(conf *config.Config, key string) (queueSettings, error) {
+	s := queueSettings{
+		workers:    defaultWorkers,
+		qps:        defaultQPS,
+		burst:      defaultBurst,
+		baseDelay:  defaultBaseDelay,
+		maxDelay:   defaultMaxDelay,
+		maxRetries: defaultMaxRetries,
+	}
+	if err := s.override(conf.Queue); err != nil {
+		return s, err
+	}
+	if q, ok := conf.Queues[key]; ok {
+		if err := s.override(q); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+func (s *queueSettings) override(q config.Queue) error {
+	if q.Workers > 0 {
+		s.workers = q.Workers
+	}
+	if q.QPS > 0 {
+		s.qps = q.QPS
+	}
+	if q.Burst > 0 {
+		s.burst = q.Burst
+	}
+	var err error
+	if s.baseDelay, err = durationOrDefault(q.BaseDelay, s.baseDelay); err != nil {
+		return err
+	}
+	if s.maxDelay, err = durationOrDefault(q.MaxDelay, s.maxDelay); err != nil {
+		return err
+	}
+	switch {
+	case q.MaxRetries > 0:
+		s.maxRetries = q.MaxRetries
+	case q.MaxRetries < 0:
+		s.maxRetries = 0
+	}
+	return nil
+}
+
+// shardedQueue spreads the events over one queue per worker by object key,
+// the events of an object are processed in order by the worker of its shard
+type shardedQueue struct {
+	shards []*shard
+	// seq numbers the events in the order they were added
+	seq uint64
+}
+
+// shard is one queue of a shardedQueue, while an event of an object waits
+// for a retry the later events of the object are held back in order
+type shard struct {
+	workqueue.RateLimitingInterface
+
+	mu sync.Mutex
+	// retrying holds the event of an object waiting for a retry by object key,
+	// an event without id holds the object back until the shutdown
+	retrying map[string]Event
+	// held holds the events of the objects waiting for a retry by object key
+	held map[string][]Event
+}
+
+func newShardedQueue(s queueSettings) *shardedQueue {
+	q := &shardedQueue{}
+	for i := 0; i < s.workers; i++ {
+		// every shard has its own limiters, the rate of retries is shared out between them
+		q.shards = append(q.shards, &shard{
+			RateLimitingInterface: workqueue.NewRateLimitingQueue(workqueue.NewMaxOfRateLimiter(
+				workqueue.NewItemExponentialFailureRateLimiter(s.baseDelay, s.maxDelay),
+				&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(s.qps/float64(s.workers)), burstOf(s.burst, s.workers))},
+			)),
+			retrying: map[string]Event{},
+			held:     map[string][]Event{},
+		})
+	}
+	return q
+}
+
+// burstOf shares burst out between workers, every worker gets at least one
+func burstOf(burst, workers int) int {
+	if b := burst / workers; b > 0 {
+		return b
+	}
+	return 1
+}
+
+// Add queues e on the shard of its object
+func (q *shardedQueue) Add(e Event) {
+	e.seq = atomic.AddUint64(&q.seq, 1)
+	h := fnv.New32a()
+	h.Write([]byte(e.key))
+	q.shards[h.Sum32()%uint32(len(q.shards))].add(e)
+}
+
+// Len returns the number of events waiting in every shard
+func (q *shardedQueue) Len() int {
+	n := 0
+	for _, shard := range q.shards {
+		n += shard.len()
+	}
+	return n
+}
+
+// ShutDown shuts every shard down, the events they hold are still handed out
+func (q *shardedQueue) ShutDown() {
+	for _, shard := range q.shards {
+		shard.ShutDown()
+	}
+}
+
+func (s *shard) add(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.retrying[e.key]; ok {
+		s.hold(e)
+		return
+	}
+	s.Add(e)
+}
+
+// hold holds e back in the order of the events, the events queued before
+// the failure of an earlier event are only held back once handed out
+func (s *shard) hold(e Event) {
+	held := s.held[e.key]
+	i := sort.Search(len(held), func(i int) bool { return held[i].seq > e.seq })
+	held = append(held, Event{})
+	copy(held[i+1:], held[i:])
+	held[i] = e
+	s.held[e.key] = held
+}
+
+// next returns the next event to process, false once the shard is shut down and empty
+func (s *shard) next() (Event, bool) {
+	for {
+		item, quit := s.Get()
+		if quit {
+			return Event{}, false
+		}
+		e := item.(Event)
+		s.mu.Lock()
+		r, ok := s.retrying[e.key]
+		if ok && r.id != e.id {
+			s.hold(e)
+			s.mu.Unlock()
+			s.Done(item)
+			continue
+		}
+		s.mu.Unlock()
+		return e, true
+	}
+}
+
+// retry queues e again after its backoff, the later events of its object are held
+// back meanwhile, false if the shard is shutting down and e is not retried
+func (s *shard) retry(e Event) bool {
+	if s.ShuttingDown() {
+		return false
+	}
+	s.mu.Lock()
+	s.retrying[e.key] = e
+	s.mu.Unlock()
+	s.AddRateLimited(e)
+	return true
+}
+
+// finish ends the retries of e, delivered or given up, and queues
+// the events of its object held back behind it
+func (s *shard) finish(e Event) {
+	s.Forget(e)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.retrying[e.key]; !ok || r.id != e.id {
+		return
+	}
+	if s.ShuttingDown() {
+		// the shard takes no event anymore, the held events are left to leftovers
+		// and the object stays held back so that they are delivered first
+		s.retrying[e.key] = Event{}
+		return
+	}
+	delete(s.retrying, e.key)
+	for _, held := range s.held[e.key] {
+		s.Add(held)
+	}
+	delete(s.held, e.key)
+}
+
+// leftovers returns the events left once the shard is shut down and empty,
+// those waiting for a retry and those held back behind them, in order
+func (s *shard) leftovers() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []Event
+	for key, r := range s.retrying {
+		if r.id != "" {
+			events = append(events, r)
+		}
+		events = append(events, s.held[key]...)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].seq < events[j].seq })
+	s.retrying = map[string]Event{}
+	s.held = map[string][]Event{}
+	return events
+}
+
+// len returns the number of events waiting in the shard, held back or not
+func (s *shard) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.Len()
+	for _, held := range s.held {
+		n += len(held)
+	}
+	return n
+}
@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+)
+
+func TestNewQueueSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.Config
+		key     string
+		want    queueSettings
+		wantErr bool
+	}{
+		{
+			"defaults",
+			config.Config{},
+			"po",
+			queueSettings{1, 10, 100, 500 * time.Millisecond, 30 * time.Second, 5},
+			false,
+		},
+		{
+			"global queue",
+			config.Config{Queue: config.Queue{Workers: 4, BaseDelay: "1s", MaxRetries: 3}},
+			"po",
+			queueSettings{4, 10, 100, time.Second, 30 * time.Second, 3},
+			false,
+		},
+		{
+			"queue of the resource",
+			config.Config{
+				Queue:  config.Queue{Workers: 4, QPS: 20},
+				Queues: map[string]config.Queue{"po": {Workers: 8, Burst: 10}},
+			},
+			"po",
+			queueSettings{8, 20, 10, 500 * time.Millisecond, 30 * time.Second, 5},
+			false,
+		},
+		{
+			"queue of another resource",
+			config.Config{Queues: map[string]config.Queue{"deploy": {Workers: 8}}},
+			"po",
+			queueSettings{1, 10, 100, 500 * time.Millisecond, 30 * time.Second, 5},
+			false,
+		},
+		{
+			"retries disabled",
+			config.Config{Queue: config.Queue{MaxRetries: -1}},
+			"po",
+			queueSettings{1, 10, 100, 500 * time.Millisecond, 30 * time.Second, 0},
+			false,
+		},
+		{
+			"invalid delay",
+			config.Config{Queue: config.Queue{MaxDelay: "soon"}},
+			"po",
+			queueSettings{},
+			true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := newQueueSettings(&test.conf, test.key)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("newQueueSettings() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if err == nil && got != test.want {
+				t.Errorf("newQueueSettings() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func testQueue(workers int) *shardedQueue {
+	return newShardedQueue(queueSettings{workers, 1000, 1000, 100 * time.Millisecond, 100 * time.Millisecond, 5})
+}
+
+// next returns the next event of s, failing after a second
+func next(t *testing.T, s *shard) Event {
+	t.Helper()
+	got := make(chan Event, 1)
+	go func() {
+		e, ok := s.next()
+		if ok {
+			got <- e
+		}
+		close(got)
+	}()
+	select {
+	case e, ok := <-got:
+		if !ok {
+			t.Fatalf("next() returned no event")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatalf("next() returned no event within a second")
+	}
+	return Event{}
+}
+
+func ids(events []Event) []string {
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.id)
+	}
+	return ids
+}
+
+func TestShardedQueueShards(t *testing.T) {
+	q := testQueue(4)
+	for i := 0; i < 100; i++ {
+		q.Add(Event{id: string(rune('a' + i%26)), key: "default/web"})
+	}
+	for i, s := range q.shards {
+		if n := s.len(); n != 0 && n != 100 {
+			t.Errorf("shard %d holds %d events of a single object, want 0 or 100", i, n)
+		}
+	}
+	if q.Len() != 100 {
+		t.Errorf("Len() = %d, want 100", q.Len())
+	}
+}
+
+func TestShardedQueueHoldsBackDuringRetry(t *testing.T) {
+	q := testQueue(1)
+	s := q.shards[0]
+	q.Add(Event{id: "1", key: "default/web"})
+	q.Add(Event{id: "2", key: "default/web"})
+	q.Add(Event{id: "3", key: "default/db"})
+
+	first := next(t, s)
+	if first.id != "1" {
+		t.Fatalf("next() = %s, want 1", first.id)
+	}
+	if !s.retry(first) {
+		t.Fatalf("retry() = false, want true")
+	}
+	s.Done(first)
+	// queued after the failure, held back right away
+	q.Add(Event{id: "4", key: "default/web"})
+
+	// 2 is handed out before the retry of 1 and held back, 3 is another object
+	if e := next(t, s); e.id != "3" {
+		t.Fatalf("next() = %s, want 3", e.id)
+	}
+	if q.Len() != 2 {
+		t.Errorf("Len() = %d, want the 2 held back events", q.Len())
+	}
+	retried := next(t, s)
+	if retried.id != "1" {
+		t.Fatalf("next() = %s, want the retry of 1", retried.id)
+	}
+	s.finish(retried)
+	s.Done(retried)
+
+	var got []Event
+	for len(got) < 2 {
+		e := next(t, s)
+		s.finish(e)
+		s.Done(e)
+		got = append(got, e)
+	}
+	if want := []string{"2", "4"}; !reflect.DeepEqual(ids(got), want) {
+		t.Errorf("events after the retry = %v, want %v", ids(got), want)
+	}
+}
+
+func TestShardedQueueLeftovers(t *testing.T) {
+	q := testQueue(1)
+	s := q.shards[0]
+	q.Add(Event{id: "1", key: "default/web"})
+	q.Add(Event{id: "2", key: "default/web"})
+	q.Add(Event{id: "3", key: "default/db"})
+
+	first := next(t, s)
+	s.retry(first)
+	s.Done(first)
+	q.ShutDown()
+	if s.retry(first) {
+		t.Errorf("retry() = true on shutdown, want false")
+	}
+
+	// 2 is held back, 3 is handed out, the retry of 1 is dropped by the shut down queue
+	if e := next(t, s); e.id != "3" {
+		t.Fatalf("next() = %s, want 3", e.id)
+	}
+	if _, ok := s.next(); ok {
+		t.Fatalf("next() returned an event after the shutdown")
+	}
+	if got, want := ids(s.leftovers()), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("leftovers() = %v, want %v", got, want)
+	}
+	if got := s.leftovers(); len(got) != 0 {
+		t.Errorf("leftovers() = %v twice, want none", ids(got))
+	}
+}
@@ -0,0 +1,37 @@
+package controller
+
+import "sync"
+
+// controllersReady backs /readyz
+var controllersReady readiness
+
+// readiness tracks the controllers of the current run
+type readiness struct {
+	mu          sync.Mutex
+	started     bool
+	controllers []*Controller
+}
+
+// set replaces the controllers of the current run, nil when there is no run,
+// e.g. on a replica which is not the leader
+func (r *readiness) set(controllers []*Controller) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = true
+	r.controllers = controllers
+}
+
+// synced reports whether every controller of the current run synced its informer
+func (r *readiness) synced() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.started {
+		return false
+	}
+	for _, c := range r.controllers {
+		if !c.hasSynced() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReadiness(t *testing.T) {
+	var r readiness
+	if r.synced() {
+		t.Error("synced() = true before the controllers started, want false")
+	}
+
+	// a replica which is not the leader runs no controller
+	r.set(nil)
+	if !r.synced() {
+		t.Error("synced() = false without controllers, want true")
+	}
+
+	f := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	c := &Controller{informer: f.Core().V1().Pods().Informer()}
+	r.set([]*Controller{c})
+	if r.synced() {
+		t.Error("synced() = true before the informer synced, want false")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	f.Start(stop)
+	f.WaitForCacheSync(stop)
+	if !r.synced() {
+		t.Error("synced() = false once the informer synced, want true")
+	}
+}
@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resource describes a typed resource which can be watched,
+// adding a kind to k8swatch is one entry of the registry
+type resource struct {
+	// key is the resource flag, e.g. po
+	key string
+	// resourceType names the resource in logs
+	resourceType string
+	// name is the plural name served by the api, e.g. pods
+	name       string
+	namespaced bool
+	enabled    func(r *config.Resource) bool
+	// versions are tried in order, the first one served by the cluster is watched
+	versions []version
+}
+
+// version is one api group version serving a resource
+type version struct {
+	groupVersion string
+	informer     func(f informers.SharedInformerFactory) cache.SharedIndexInformer
+}
+
+var registry = []resource{
+	{
+		"po",
+		"pod",
+		"pods",
+		true,
+		func(r *config.Resource) bool { return r.Pod },
+		[]version{
+			{"v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Core().V1().Pods().Informer()
+			}},
+		},
+	},
+	{
+		"ds",
+		"daemonset",
+		"daemonsets",
+		true,
+		func(r *config.Resource) bool { return r.DaemonSet },
+		[]version{
+			{"apps/v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Apps().V1().DaemonSets().Informer()
+			}},
+			{"extensions/v1beta1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Extensions().V1beta1().DaemonSets().Informer()
+			}},
+		},
+	},
+	{
+		"rs",
+		"replicaset",
+		"replicasets",
+		true,
+		func(r *config.Resource) bool { return r.ReplicaSet },
+		[]version{
+			{"apps/v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Apps().V1().ReplicaSets().Informer()
+			}},
+			{"extensions/v1beta1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Extensions().V1beta1().ReplicaSets().Informer()
+			}},
+		},
+	},
+	{
+		"svc",
+		"service",
+		"services",
+		true,
+		func(r *config.Resource) bool { return r.Service },
+		[]version{
+			{"v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Core().V1().Services().Informer()
+			}},
+		},
+	},
+	{
+		"deploy",
+		"deployment",
+		"deployments",
+		true,
+		func(r *config.Resource) bool { return r.Deployment },
+		[]version{
+			{"apps/v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Apps().V1().Deployments().Informer()
+			}},
+			{"extensions/v1beta1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Extensions().V1beta1().Deployments().Informer()
+			}},
+		},
+	},
+	{
+		"ns",
+		"namespace",
+		"namespaces",
+		false,
+		func(r *config.Resource) bool { return r.Namespace },
+		[]version{
+			{"v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Core().V1().Namespaces().Informer()
+			}},
+		},
+	},
+	{
+		"rc",
+		"replication controller",
+		"replicationcontrollers",
+		true,
+		func(r *config.Resource) bool { return r.ReplicationController },
+		[]version{
+			{"v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Core().V1().ReplicationControllers().Informer()
+			}},
+		},
+	},
+	{
+		"job",
+		"job",
+		"jobs",
+		true,
+		func(r *config.Resource) bool { return r.Job },
+		[]version{
+			{"batch/v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Batch().V1().Jobs().Informer()
+			}},
+		},
+	},
+	{
+		"pv",
+		"persistent volume",
+		"persistentvolumes",
+		false,
+		func(r *config.Resource) bool { return r.PersistentVolume },
+		[]version{
+			{"v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Core().V1().PersistentVolumes().Informer()
+			}},
+		},
+	},
+	{
+		"secret",
+		"secret",
+		"secrets",
+		true,
+		func(r *config.Resource) bool { return r.Secret },
+		[]version{
+			{"v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Core().V1().Secrets().Informer()
+			}},
+		},
+	},
+	{
+		"cm",
+		"configmap",
+		"configmaps",
+		true,
+		func(r *config.Resource) bool { return r.ConfigMap },
+		[]version{
+			{"v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Core().V1().ConfigMaps().Informer()
+			}},
+		},
+	},
+	{
+		"ing",
+		"ingress",
+		"ingresses",
+		true,
+		func(r *config.Resource) bool { return r.Ingress },
+		[]version{
+			{"networking.k8s.io/v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Networking().V1().Ingresses().Informer()
+			}},
+			{"networking.k8s.io/v1beta1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Networking().V1beta1().Ingresses().Informer()
+			}},
+			{"extensions/v1beta1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Extensions().V1beta1().Ingresses().Informer()
+			}},
+		},
+	},
+	{
+		"ev",
+		"event",
+		"events",
+		true,
+		func(r *config.Resource) bool { return r.Event },
+		[]version{
+			{"v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Core().V1().Events().Informer()
+			}},
+		},
+	},
+	{
+		"no",
+		"node",
+		"nodes",
+		false,
+		func(r *config.Resource) bool { return r.Node },
+		[]version{
+			{"v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Core().V1().Nodes().Informer()
+			}},
+		},
+	},
+	{
+		"sts",
+		"statefulset",
+		"statefulsets",
+		true,
+		func(r *config.Resource) bool { return r.StatefulSet },
+		[]version{
+			{"apps/v1", func(f informers.SharedInformerFactory) cache.SharedIndexInformer {
+				return f.Apps().V1().StatefulSets().Informer()
+			}},
+		},
+	},
+}
+
+// lookup returns the registry entry of the resource flag key
+func lookup(key string) resource {
+	for _, r := range registry {
+		if r.key == key {
+			return r
+		}
+	}
+	panic("unknown resource " + key)
+}
+
+// errNotServed is returned by servedVersion when the cluster serves no version of a resource
+var errNotServed = errors.New("no served api version")
+
+// servedVersion returns the first version of r served by the cluster, errNotServed
+// if there is none, a group version failing to be discovered is an error
+func servedVersion(client discovery.DiscoveryInterface, r resource) (version, error) {
+	for _, v := range r.versions {
+		resources, err := client.ServerResourcesForGroupVersion(v.groupVersion)
+		if apierrors.IsNotFound(err) {
+			// the group version does not exist on this cluster
+			continue
+		}
+		if err != nil {
+			return version{}, fmt.Errorf("failed to discover %s: %v", v.groupVersion, err)
+		}
+		for _, apiResource := range resources.APIResources {
+			if apiResource.Name == r.name {
+				return v, nil
+			}
+		}
+	}
+	return version{}, errNotServed
+}
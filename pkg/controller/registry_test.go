@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8s_testing "k8s.io/client-go/testing"
+)
+
+func TestRegistry(t *testing.T) {
+	f := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	keys := map[string]bool{}
+	for _, r := range registry {
+		if keys[r.key] {
+			t.Errorf("resource %s is registered twice", r.key)
+		}
+		keys[r.key] = true
+
+		if r.enabled(&config.Resource{}) {
+			t.Errorf("resource %s is enabled by an empty config", r.key)
+		}
+		if len(r.versions) == 0 {
+			t.Errorf("resource %s has no version", r.key)
+		}
+		for _, v := range r.versions {
+			if v.informer(f) == nil {
+				t.Errorf("resource %s has no informer for %s", r.key, v.groupVersion)
+			}
+		}
+	}
+
+	if r := lookup("deploy"); r.name != "deployments" || !r.enabled(&config.Resource{Deployment: true}) {
+		t.Errorf("lookup(deploy) = %s, want deployments enabled by Resource.Deployment", r.name)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("lookup() of an unknown resource did not panic")
+		}
+	}()
+	lookup("widgets")
+}
+
+func TestServedVersion(t *testing.T) {
+	deployments := &meta_v1.APIResourceList{GroupVersion: "apps/v1", APIResources: []meta_v1.APIResource{{Name: "deployments"}}}
+	legacyDeployments := &meta_v1.APIResourceList{GroupVersion: "extensions/v1beta1", APIResources: []meta_v1.APIResource{{Name: "deployments"}}}
+	legacyIngresses := &meta_v1.APIResourceList{GroupVersion: "extensions/v1beta1", APIResources: []meta_v1.APIResource{{Name: "ingresses"}}}
+	ingresses := &meta_v1.APIResourceList{GroupVersion: "networking.k8s.io/v1", APIResources: []meta_v1.APIResource{{Name: "ingresses"}}}
+
+	tests := []struct {
+		name    string
+		key     string
+		served  []*meta_v1.APIResourceList
+		want    string
+		wantErr bool
+	}{
+		{"current version", "deploy", []*meta_v1.APIResourceList{legacyDeployments, deployments}, "apps/v1", false},
+		{"legacy version", "deploy", []*meta_v1.APIResourceList{legacyDeployments}, "extensions/v1beta1", false},
+		{"other resource of the group version", "deploy", []*meta_v1.APIResourceList{legacyIngresses}, "", true},
+		{"networking", "ing", []*meta_v1.APIResourceList{legacyIngresses, ingresses}, "networking.k8s.io/v1", false},
+		{"not served", "sts", nil, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := servedVersion(fakeDiscovery(test.served...), lookup(test.key))
+			if (err == errNotServed) != test.wantErr {
+				t.Fatalf("servedVersion() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if v.groupVersion != test.want {
+				t.Errorf("servedVersion() = %q, want %q", v.groupVersion, test.want)
+			}
+		})
+	}
+}
+
+func TestServedVersionDiscoveryError(t *testing.T) {
+	d := fakeDiscovery(&meta_v1.APIResourceList{GroupVersion: "extensions/v1beta1", APIResources: []meta_v1.APIResource{{Name: "deployments"}}})
+	d.PrependReactor("get", "resource", func(action k8s_testing.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{}, "apps/v1", errors.New("denied"))
+	})
+	// a version failing to be discovered is not skipped for an older one
+	if v, err := servedVersion(d, lookup("deploy")); err == nil || err == errNotServed {
+		t.Errorf("servedVersion() = %q, %v, want the discovery error", v.groupVersion, err)
+	}
+}
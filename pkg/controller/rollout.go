@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/handlers"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	apps_listers "k8s.io/client-go/listers/apps/v1"
+)
+
+// rolloutProgressInterval is the minimum time between two progress notifications of a rollout
+const rolloutProgressInterval = 30 * time.Second
+
+// revisionAnnotation is the revision of a Deployment and of its ReplicaSets
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// rolloutStatus is the state of a workload rollout, whatever its kind
+type rolloutStatus struct {
+	object   interface{}
+	uid      types.UID
+	template api_v1.PodTemplateSpec
+	// desired, updated and available count pods
+	desired   int32
+	updated   int32
+	available int32
+	// complete once the controller observed the spec and every pod is updated and available
+	complete bool
+	// stalled is the reason the rollout stopped progressing, if any
+	stalled string
+	// unavailable is the reason the workload is not available, if any
+	unavailable string
+	// detail locates the rollout, e.g. the new ReplicaSet of a Deployment
+	detail string
+}
+
+// rolloutStatusFunc returns the rollout status of obj, false if obj has another kind
+type rolloutStatusFunc func(obj interface{}) (rolloutStatus, bool)
+
+// rollouts tracks the rollouts in progress by object uid
+type rollouts struct {
+	mu     sync.Mutex
+	active map[types.UID]*rolloutProgress
+}
+
+type rolloutProgress struct {
+	started      time.Time
+	lastProgress time.Time
+}
+
+// newRolloutAnalyzer returns an analyzer following rollouts from a change of the pod template
+// to their completion or stall, status reads the rollout of the analyzed kind
+func newRolloutAnalyzer(status rolloutStatusFunc) analyzer {
+	r := &rollouts{active: map[types.UID]*rolloutProgress{}}
+
+	return func(old, new interface{}) []handlers.Notification {
+		if new == nil {
+			if oldStatus, ok := status(old); ok {
+				r.mu.Lock()
+				delete(r.active, oldStatus.uid)
+				r.mu.Unlock()
+			}
+			return nil
+		}
+		newStatus, ok := status(new)
+		if !ok || old == nil {
+			return nil
+		}
+		oldStatus, ok := status(old)
+		if !ok {
+			return nil
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		var notifications []handlers.Notification
+		notify := func(reason, severity, format string, args ...interface{}) {
+			notifications = append(notifications, handlers.Notification{
+				Reason:   reason,
+				Message:  fmt.Sprintf(format, args...),
+				Severity: severity,
+				Object:   newStatus.object,
+			})
+		}
+
+		now := time.Now()
+		progress, active := r.active[newStatus.uid]
+
+		if !equality.Semantic.DeepEqual(oldStatus.template, newStatus.template) {
+			progress = &rolloutProgress{started: now, lastProgress: now}
+			r.active[newStatus.uid] = progress
+			active = true
+			notify("RolloutStarted", handlers.SeverityInfo, "rollout started (%s)", templateChange(oldStatus.template, newStatus.template))
+		}
+
+		if !active {
+			return notifications
+		}
+
+		switch {
+		case newStatus.stalled != "" && oldStatus.stalled == "":
+			delete(r.active, newStatus.uid)
+			notify("RolloutStalled", handlers.SeverityCritical, "rollout stalled after %s: %s", formatDuration(now.Sub(progress.started), true), newStatus.stalled)
+		case newStatus.complete:
+			delete(r.active, newStatus.uid)
+			notify("RolloutComplete", handlers.SeverityInfo, "rollout complete in %s", formatDuration(now.Sub(progress.started), true))
+		case newStatus.unavailable != "" && oldStatus.unavailable == "":
+			notify("RolloutUnavailable", handlers.SeverityWarning, "rollout in progress, unavailable: %s", newStatus.unavailable)
+		case (newStatus.updated != oldStatus.updated || newStatus.available != oldStatus.available) &&
+			now.Sub(progress.lastProgress) >= rolloutProgressInterval:
+			progress.lastProgress = now
+			message := fmt.Sprintf("rollout progress: %d/%d updated, %d/%d available",
+				newStatus.updated, newStatus.desired, newStatus.available, newStatus.desired)
+			if newStatus.detail != "" {
+				message += " (" + newStatus.detail + ")"
+			}
+			notify("RolloutProgress", handlers.SeverityInfo, "%s", message)
+		}
+
+		return notifications
+	}
+}
+
+// templateChange describes a change of a pod template by the images it changed
+func templateChange(old, new api_v1.PodTemplateSpec) string {
+	oldImages := map[string]string{}
+	for _, c := range old.Spec.Containers {
+		oldImages[c.Name] = c.Image
+	}
+
+	var changes []string
+	for _, c := range new.Spec.Containers {
+		if image, ok := oldImages[c.Name]; ok && image != c.Image {
+			changes = append(changes, fmt.Sprintf("%s: image %s → %s", c.Name, image, c.Image))
+		}
+	}
+	if len(changes) == 0 {
+		return "pod template changed"
+	}
+	return strings.Join(changes, ", ")
+}
+
+// deploymentRollout returns the rollout status of Deployments,
+// the ReplicaSet of the current revision is looked up through lister
+func deploymentRollout(lister apps_listers.ReplicaSetLister) rolloutStatusFunc {
+	return func(obj interface{}) (rolloutStatus, bool) {
+		d, ok := obj.(*apps_v1.Deployment)
+		if !ok {
+			return rolloutStatus{}, false
+		}
+
+		desired := int32(1)
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		status := rolloutStatus{
+			object:    d,
+			uid:       d.UID,
+			template:  d.Spec.Template,
+			desired:   desired,
+			updated:   d.Status.UpdatedReplicas,
+			available: d.Status.AvailableReplicas,
+			complete: d.Status.ObservedGeneration >= d.Generation &&
+				d.Status.UpdatedReplicas == desired &&
+				d.Status.Replicas == desired &&
+				d.Status.AvailableReplicas == desired,
+		}
+
+		for _, c := range d.Status.Conditions {
+			switch {
+			case c.Type == apps_v1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded":
+				status.stalled = c.Reason + ": " + c.Message
+				status.complete = false
+			case c.Type == apps_v1.DeploymentAvailable && c.Status == api_v1.ConditionFalse:
+				status.unavailable = c.Reason + ": " + c.Message
+			}
+		}
+
+		if rs := newReplicaSet(lister, d); rs != nil {
+			status.detail = fmt.Sprintf("replicaset %s %d/%d ready", rs.Name, rs.Status.ReadyReplicas, desired)
+		}
+		return status, true
+	}
+}
+
+// newReplicaSet returns the ReplicaSet controlled by d at its current revision, nil if it is not known yet
+func newReplicaSet(lister apps_listers.ReplicaSetLister, d *apps_v1.Deployment) *apps_v1.ReplicaSet {
+	selector, err := meta_v1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	replicaSets, err := lister.ReplicaSets(d.Namespace).List(selector)
+	if err != nil {
+		return nil
+	}
+	for _, rs := range replicaSets {
+		if owner := meta_v1.GetControllerOf(rs); owner == nil || owner.UID != d.UID {
+			continue
+		}
+		if rs.Annotations[revisionAnnotation] == d.Annotations[revisionAnnotation] {
+			return rs
+		}
+	}
+	return nil
+}
+
+// daemonSetRollout returns the rollout status of DaemonSets
+func daemonSetRollout(obj interface{}) (rolloutStatus, bool) {
+	ds, ok := obj.(*apps_v1.DaemonSet)
+	if !ok {
+		return rolloutStatus{}, false
+	}
+
+	desired := ds.Status.DesiredNumberScheduled
+	return rolloutStatus{
+		object:    ds,
+		uid:       ds.UID,
+		template:  ds.Spec.Template,
+		desired:   desired,
+		updated:   ds.Status.UpdatedNumberScheduled,
+		available: ds.Status.NumberAvailable,
+		complete: ds.Status.ObservedGeneration >= ds.Generation &&
+			ds.Status.UpdatedNumberScheduled == desired &&
+			ds.Status.NumberAvailable == desired,
+	}, true
+}
+
+// statefulSetRollout returns the rollout status of StatefulSets
+func statefulSetRollout(obj interface{}) (rolloutStatus, bool) {
+	sts, ok := obj.(*apps_v1.StatefulSet)
+	if !ok {
+		return rolloutStatus{}, false
+	}
+
+	desired := int32(1)
+	if sts.Spec.Replicas != nil {
+		desired = *sts.Spec.Replicas
+	}
+	status := rolloutStatus{
+		object:    sts,
+		uid:       sts.UID,
+		template:  sts.Spec.Template,
+		desired:   desired,
+		updated:   sts.Status.UpdatedReplicas,
+		available: sts.Status.AvailableReplicas,
+		complete: sts.Status.ObservedGeneration >= sts.Generation &&
+			sts.Status.UpdatedReplicas == desired &&
+			sts.Status.ReadyReplicas == desired &&
+			sts.Status.CurrentRevision == sts.Status.UpdateRevision,
+	}
+	if sts.Status.UpdateRevision != "" {
+		status.detail = "revision " + sts.Status.UpdateRevision
+	}
+	return status, true
+}
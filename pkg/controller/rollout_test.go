@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	apps_listers "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func podTemplate(image string) api_v1.PodTemplateSpec {
+	return api_v1.PodTemplateSpec{Spec: api_v1.PodSpec{Containers: []api_v1.Container{{Name: "web", Image: image}}}}
+}
+
+// rolloutDeployment returns a Deployment of 3 replicas running image at generation 2,
+// observed is the generation its controller saw, updated and available count its pods
+func rolloutDeployment(image string, observed int64, updated, available int32, conditions ...apps_v1.DeploymentCondition) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "web",
+			UID:         "d1",
+			Generation:  2,
+			Annotations: map[string]string{revisionAnnotation: "2"},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: int32Ptr(3),
+			Selector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: podTemplate(image),
+		},
+		Status: apps_v1.DeploymentStatus{
+			ObservedGeneration: observed,
+			Replicas:           3,
+			UpdatedReplicas:    updated,
+			AvailableReplicas:  available,
+			Conditions:         conditions,
+		},
+	}
+}
+
+var (
+	stalledCondition     = apps_v1.DeploymentCondition{Type: apps_v1.DeploymentProgressing, Status: api_v1.ConditionFalse, Reason: "ProgressDeadlineExceeded", Message: "timed out"}
+	unavailableCondition = apps_v1.DeploymentCondition{Type: apps_v1.DeploymentAvailable, Status: api_v1.ConditionFalse, Reason: "MinimumReplicasUnavailable", Message: "2 of 3 available"}
+)
+
+// replicaSetLister lists the ReplicaSets
+func replicaSetLister(t *testing.T, replicaSets ...*apps_v1.ReplicaSet) apps_listers.ReplicaSetLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, rs := range replicaSets {
+		if err := indexer.Add(rs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return apps_listers.NewReplicaSetLister(indexer)
+}
+
+func TestRolloutAnalyzer(t *testing.T) {
+	type step struct {
+		old, new interface{}
+		want     []string
+	}
+	stable := rolloutDeployment("nginx:1", 2, 3, 3)
+	started := rolloutDeployment("nginx:2", 1, 0, 3)
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"created", []step{{nil, stable, nil}}},
+		{"not a workload", []step{{&api_v1.Pod{}, &api_v1.Pod{}, nil}}},
+		{"no rollout", []step{{stable, rolloutDeployment("nginx:1", 2, 3, 2), nil}}},
+		{"started", []step{{stable, started, []string{"RolloutStarted|info"}}}},
+		{
+			"complete",
+			[]step{
+				{stable, started, []string{"RolloutStarted|info"}},
+				{started, rolloutDeployment("nginx:2", 2, 3, 3), []string{"RolloutComplete|info"}},
+				{rolloutDeployment("nginx:2", 2, 3, 3), rolloutDeployment("nginx:2", 2, 3, 3), nil},
+			},
+		},
+		{
+			"stalled",
+			[]step{
+				{stable, started, []string{"RolloutStarted|info"}},
+				{started, rolloutDeployment("nginx:2", 2, 1, 2, stalledCondition), []string{"RolloutStalled|critical"}},
+				{rolloutDeployment("nginx:2", 2, 1, 2, stalledCondition), rolloutDeployment("nginx:2", 2, 1, 2, stalledCondition), nil},
+			},
+		},
+		{
+			"unavailable",
+			[]step{
+				{stable, started, []string{"RolloutStarted|info"}},
+				{started, rolloutDeployment("nginx:2", 2, 1, 2, unavailableCondition), []string{"RolloutUnavailable|warning"}},
+				{rolloutDeployment("nginx:2", 2, 1, 2, unavailableCondition), rolloutDeployment("nginx:2", 2, 2, 2, unavailableCondition), nil},
+			},
+		},
+		{
+			"progress within the interval",
+			[]step{
+				{stable, started, []string{"RolloutStarted|info"}},
+				{started, rolloutDeployment("nginx:2", 2, 1, 3), nil},
+			},
+		},
+		{
+			"deleted during a rollout",
+			[]step{
+				{stable, started, []string{"RolloutStarted|info"}},
+				{started, nil, nil},
+				{started, rolloutDeployment("nginx:2", 2, 3, 3), nil},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			analyze := newRolloutAnalyzer(deploymentRollout(replicaSetLister(t)))
+			for i, s := range test.steps {
+				if got := reasons(analyze(s.old, s.new)); !reflect.DeepEqual(got, s.want) {
+					t.Errorf("step %d: newRolloutAnalyzer() = %q, want %q", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateChange(t *testing.T) {
+	sidecar := podTemplate("nginx:1")
+	sidecar.Spec.Containers = append(sidecar.Spec.Containers, api_v1.Container{Name: "proxy", Image: "envoy:1"})
+
+	tests := []struct {
+		name     string
+		old, new api_v1.PodTemplateSpec
+		want     string
+	}{
+		{"image", podTemplate("nginx:1"), podTemplate("nginx:2"), "web: image nginx:1 → nginx:2"},
+		{"added container", podTemplate("nginx:1"), sidecar, "pod template changed"},
+		{
+			"labels",
+			podTemplate("nginx:1"),
+			api_v1.PodTemplateSpec{ObjectMeta: meta_v1.ObjectMeta{Labels: map[string]string{"a": "b"}}, Spec: podTemplate("nginx:1").Spec},
+			"pod template changed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := templateChange(test.old, test.new); got != test.want {
+				t.Errorf("templateChange() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func replicaSet(name, revision, owner string) *apps_v1.ReplicaSet {
+	controller := true
+	return &apps_v1.ReplicaSet{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Namespace:       "default",
+			Name:            name,
+			Labels:          map[string]string{"app": "web"},
+			Annotations:     map[string]string{revisionAnnotation: revision},
+			OwnerReferences: []meta_v1.OwnerReference{{Kind: "Deployment", Name: "web", UID: types.UID(owner), Controller: &controller}},
+		},
+		Status: apps_v1.ReplicaSetStatus{ReadyReplicas: 2},
+	}
+}
+
+func TestDeploymentRolloutDetail(t *testing.T) {
+	tests := []struct {
+		name        string
+		replicaSets []*apps_v1.ReplicaSet
+		want        string
+	}{
+		{"no replicaset", nil, ""},
+		{"current revision", []*apps_v1.ReplicaSet{replicaSet("web-1", "1", "d1"), replicaSet("web-2", "2", "d1")}, "replicaset web-2 2/3 ready"},
+		{"previous revision only", []*apps_v1.ReplicaSet{replicaSet("web-1", "1", "d1")}, ""},
+		{"another owner", []*apps_v1.ReplicaSet{replicaSet("web-2", "2", "d2")}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, ok := deploymentRollout(replicaSetLister(t, test.replicaSets...))(rolloutDeployment("nginx:2", 2, 1, 2))
+			if !ok || status.detail != test.want {
+				t.Errorf("deploymentRollout() detail = %q, %v, want %q", status.detail, ok, test.want)
+			}
+		})
+	}
+}
+
+func TestRolloutComplete(t *testing.T) {
+	daemonSet := func(observed int64, updated, available int32) *apps_v1.DaemonSet {
+		return &apps_v1.DaemonSet{
+			ObjectMeta: meta_v1.ObjectMeta{Generation: 2},
+			Status: apps_v1.DaemonSetStatus{
+				ObservedGeneration:     observed,
+				DesiredNumberScheduled: 3,
+				UpdatedNumberScheduled: updated,
+				NumberAvailable:        available,
+			},
+		}
+	}
+	statefulSet := func(updated, ready int32, current string) *apps_v1.StatefulSet {
+		return &apps_v1.StatefulSet{
+			ObjectMeta: meta_v1.ObjectMeta{Generation: 2},
+			Spec:       apps_v1.StatefulSetSpec{Replicas: int32Ptr(3)},
+			Status: apps_v1.StatefulSetStatus{
+				ObservedGeneration: 2,
+				UpdatedReplicas:    updated,
+				ReadyReplicas:      ready,
+				CurrentRevision:    current,
+				UpdateRevision:     "web-2",
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		status rolloutStatusFunc
+		obj    interface{}
+		want   bool
+	}{
+		{"deployment", deploymentRollout(replicaSetLister(t)), rolloutDeployment("nginx:2", 2, 3, 3), true},
+		{"deployment not observed", deploymentRollout(replicaSetLister(t)), rolloutDeployment("nginx:2", 1, 3, 3), false},
+		{"deployment stalled", deploymentRollout(replicaSetLister(t)), rolloutDeployment("nginx:2", 2, 3, 3, stalledCondition), false},
+		{"daemonset", daemonSetRollout, daemonSet(2, 3, 3), true},
+		{"daemonset not observed", daemonSetRollout, daemonSet(1, 3, 3), false},
+		{"daemonset unavailable", daemonSetRollout, daemonSet(2, 3, 2), false},
+		{"statefulset", statefulSetRollout, statefulSet(3, 3, "web-2"), true},
+		{"statefulset previous revision", statefulSetRollout, statefulSet(3, 3, "web-1"), false},
+		{"statefulset not ready", statefulSetRollout, statefulSet(3, 2, "web-2"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, ok := test.status(test.obj)
+			if !ok || status.complete != test.want {
+				t.Errorf("complete = %v, %v, want %v", status.complete, ok, test.want)
+			}
+		})
+	}
+
+	if _, ok := daemonSetRollout(&apps_v1.StatefulSet{}); ok {
+		t.Error("daemonSetRollout() of a StatefulSet = true, want false")
+	}
+}
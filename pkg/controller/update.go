@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/diff"
+	"github.com/walk1ng/k8swatch/pkg/utils"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// updateFilter is the compiled form of config.Update
+type updateFilter struct {
+	kind               string
+	skipSameGeneration bool
+	ignore             *diff.Differ
+	paths              []*jsonpath.JSONPath
+}
+
+// alwaysIgnored change with every update, whatever the rules say
+var alwaysIgnored = []string{"metadata.resourceVersion", "metadata.managedFields"}
+
+func newUpdateFilters(rules []config.Update) ([]updateFilter, error) {
+	// updates touching nothing but the always ignored fields never fire
+	filters := []updateFilter{{ignore: diff.New(alwaysIgnored)}}
+	for i, rule := range rules {
+		f := updateFilter{
+			kind:               rule.Kind,
+			skipSameGeneration: rule.SkipSameGeneration,
+			ignore:             diff.New(append(append([]string{}, alwaysIgnored...), rule.IgnorePaths...)),
+		}
+		for _, path := range rule.Paths {
+			j := jsonpath.New(path).AllowMissingKeys(true)
+			expr := path
+			if !strings.HasPrefix(expr, "{") {
+				expr = "{" + expr + "}"
+			}
+			if err := j.Parse(expr); err != nil {
+				return nil, fmt.Errorf("invalid path %q in update rule %d: %v", path, i, err)
+			}
+			f.paths = append(f.paths, j)
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// significant reports whether an update is worth an event, every
+// filter of the kind of the object has to agree. Resyncs, where the
+// resourceVersion did not change, are never significant.
+func significant(filters []updateFilter, old, new interface{}) (bool, string) {
+	oldMeta := utils.GetObjectMetaData(old)
+	newMeta := utils.GetObjectMetaData(new)
+	if oldMeta.ResourceVersion == newMeta.ResourceVersion {
+		return false, "resync"
+	}
+
+	kind := utils.GetObjectKind(new)
+	for _, f := range filters {
+		if f.kind != "" && !strings.EqualFold(f.kind, kind) {
+			continue
+		}
+		if f.skipSameGeneration && oldMeta.Generation == newMeta.Generation {
+			return false, "generation unchanged"
+		}
+		changes, err := f.ignore.Compute(old, new)
+		if err == nil && changes.Empty() {
+			return false, "only ignored fields changed"
+		}
+		if len(f.paths) != 0 && !pathsChanged(f.paths, old, new) {
+			return false, "watched paths unchanged"
+		}
+	}
+	return true, ""
+}
+
+// pathsChanged reports whether any of the paths evaluates differently on old and new
+func pathsChanged(paths []*jsonpath.JSONPath, old, new interface{}) bool {
+	for _, j := range paths {
+		var oldValue, newValue bytes.Buffer
+		if err := j.Execute(&oldValue, jsonpathData(old)); err != nil {
+			return true
+		}
+		if err := j.Execute(&newValue, jsonpathData(new)); err != nil {
+			return true
+		}
+		if oldValue.String() != newValue.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonpathData returns what a JSONPath is evaluated on, typed objects
+// are walked through their json tags
+func jsonpathData(obj interface{}) interface{} {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return u.UnstructuredContent()
+	}
+	return obj
+}
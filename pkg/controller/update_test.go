@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+// deploymentVersion returns a Deployment at resourceVersion and generation,
+// change tweaks it from the defaults
+func deploymentVersion(resourceVersion string, generation int64, change func(d *apps_v1.Deployment)) *apps_v1.Deployment {
+	d := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Namespace:       "default",
+			Name:            "web",
+			ResourceVersion: resourceVersion,
+			Generation:      generation,
+		},
+		Spec: apps_v1.DeploymentSpec{Replicas: int32Ptr(1)},
+	}
+	if change != nil {
+		change(d)
+	}
+	return d
+}
+
+func TestSignificant(t *testing.T) {
+	old := deploymentVersion("1", 1, nil)
+	scaled := deploymentVersion("2", 2, func(d *apps_v1.Deployment) { d.Spec.Replicas = int32Ptr(3) })
+	status := deploymentVersion("2", 1, func(d *apps_v1.Deployment) { d.Status.ReadyReplicas = 1 })
+	annotated := deploymentVersion("2", 1, func(d *apps_v1.Deployment) { d.Annotations = map[string]string{"note": "x"} })
+	managed := deploymentVersion("2", 1, func(d *apps_v1.Deployment) {
+		d.ManagedFields = []meta_v1.ManagedFieldsEntry{{Manager: "kubectl", Operation: meta_v1.ManagedFieldsOperationApply}}
+	})
+
+	tests := []struct {
+		name       string
+		rules      []config.Update
+		old, new   interface{}
+		want       bool
+		wantReason string
+	}{
+		{"resync", nil, old, deploymentVersion("1", 1, nil), false, "resync"},
+		{"no rule", nil, old, scaled, true, ""},
+		{"managed fields only without rules", nil, old, managed, false, "only ignored fields changed"},
+		{"managed fields only", []config.Update{{IgnorePaths: []string{"status"}}}, old, managed, false, "only ignored fields changed"},
+		{"same generation skipped", []config.Update{{SkipSameGeneration: true}}, old, status, false, "generation unchanged"},
+		{"new generation", []config.Update{{SkipSameGeneration: true}}, old, scaled, true, ""},
+		{"rule of another kind", []config.Update{{Kind: "StatefulSet", SkipSameGeneration: true}}, old, status, true, ""},
+		{"rule of the kind", []config.Update{{Kind: "deployment", SkipSameGeneration: true}}, old, status, false, "generation unchanged"},
+		{"ignored status", []config.Update{{IgnorePaths: []string{"status"}}}, old, status, false, "only ignored fields changed"},
+		{"ignored status but annotated", []config.Update{{IgnorePaths: []string{"status"}}}, old, annotated, true, ""},
+		{"watched path changed", []config.Update{{Paths: []string{".spec.replicas"}}}, old, scaled, true, ""},
+		{"watched path unchanged", []config.Update{{Paths: []string{"{.spec.replicas}"}}}, old, annotated, false, "watched paths unchanged"},
+		{"every rule agrees", []config.Update{{Paths: []string{".spec.replicas"}}, {IgnorePaths: []string{"status"}}}, old, scaled, true, ""},
+		{"one rule disagrees", []config.Update{{Paths: []string{".spec.paused"}}, {IgnorePaths: []string{"status"}}}, old, scaled, false, "watched paths unchanged"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filters, err := newUpdateFilters(test.rules)
+			if err != nil {
+				t.Fatalf("newUpdateFilters() = %v", err)
+			}
+			got, reason := significant(filters, test.old, test.new)
+			if got != test.want || reason != test.wantReason {
+				t.Errorf("significant() = %v, %q, want %v, %q", got, reason, test.want, test.wantReason)
+			}
+		})
+	}
+}
+
+func TestNewUpdateFiltersInvalidPath(t *testing.T) {
+	if _, err := newUpdateFilters([]config.Update{{Paths: []string{"{.spec"}}}); err == nil {
+		t.Error("newUpdateFilters() = nil, want an error")
+	}
+}
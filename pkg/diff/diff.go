@@ -0,0 +1,327 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// maxValueLength truncates values in the summary
+const maxValueLength = 80
+
+// redacted replaces the values of Secrets in patches and summaries
+const redacted = "<redacted>"
+
+// secretFields hold the values of Secrets, only their keys are reported
+var secretFields = map[string]bool{"data": true, "stringData": true}
+
+// DefaultIgnorePaths are the noise fields left out of diffs when the
+// configuration does not name any
+var DefaultIgnorePaths = []string{
+	"metadata.resourceVersion",
+	"metadata.managedFields",
+	"metadata.generation",
+	"status",
+}
+
+// Operation is one JSON Patch (RFC 6902) operation
+type Operation struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+// MarshalJSON leaves out the value of remove operations only,
+// add and replace keep zero values such as 0 or false
+func (o Operation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{o.Op, o.Path, o.Value})
+}
+
+// Diff describes the changes between two versions of an object
+type Diff struct {
+	// Patch turns the old object into the new one
+	Patch []Operation `json:"patch"`
+	// Summary has one human readable line per change
+	Summary []string `json:"summary"`
+
+	// secret redacts the values of data and stringData
+	secret bool
+}
+
+// Empty reports whether nothing but ignored fields changed
+func (d *Diff) Empty() bool {
+	return d == nil || len(d.Patch) == 0
+}
+
+// String returns the summary, one change per line
+func (d *Diff) String() string {
+	if d == nil {
+		return ""
+	}
+	return strings.Join(d.Summary, "\n")
+}
+
+// Differ computes diffs ignoring noise fields
+type Differ struct {
+	ignore [][]string
+}
+
+// New creates a Differ ignoring the dotted field paths, e.g. metadata.resourceVersion,
+// DefaultIgnorePaths are used when paths is empty
+func New(paths []string) *Differ {
+	if len(paths) == 0 {
+		paths = DefaultIgnorePaths
+	}
+	d := &Differ{}
+	for _, p := range paths {
+		d.ignore = append(d.ignore, strings.Split(p, "."))
+	}
+	return d
+}
+
+// Compute returns the diff between old and new
+func (d *Differ) Compute(old, new interface{}) (*Diff, error) {
+	oldMap, err := d.toMap(old)
+	if err != nil {
+		return nil, err
+	}
+	newMap, err := d.toMap(new)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Diff{secret: isSecret(new)}
+	result.compare(nil, oldMap, newMap)
+	return result, nil
+}
+
+// toMap converts obj into a copy without the ignored fields
+func (d *Differ) toMap(obj interface{}) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	switch object := obj.(type) {
+	case runtime.Unstructured:
+		m = runtime.DeepCopyJSON(object.UnstructuredContent())
+	default:
+		var err error
+		m, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %T: %v", obj, err)
+		}
+	}
+
+	for _, fields := range d.ignore {
+		unstructured.RemoveNestedField(m, fields...)
+	}
+	return m, nil
+}
+
+// compare appends the operations turning old into new at path
+func (d *Diff) compare(path []string, old, new interface{}) {
+	switch oldValue := old.(type) {
+	case map[string]interface{}:
+		newValue, ok := new.(map[string]interface{})
+		if !ok {
+			d.replace(path, old, new)
+			return
+		}
+		for _, k := range sortedKeys(oldValue, newValue) {
+			o, inOld := oldValue[k]
+			n, inNew := newValue[k]
+			p := append(append([]string{}, path...), k)
+			switch {
+			case !inNew:
+				d.remove(p, o)
+			case !inOld:
+				d.add(p, n)
+			default:
+				d.compare(p, o, n)
+			}
+		}
+	case []interface{}:
+		newValue, ok := new.([]interface{})
+		if !ok || len(oldValue) != len(newValue) {
+			d.replace(path, old, new)
+			return
+		}
+		for i := range oldValue {
+			d.compare(append(append([]string{}, path...), strconv.Itoa(i)), oldValue[i], newValue[i])
+		}
+	default:
+		if !reflect.DeepEqual(old, new) {
+			d.replace(path, old, new)
+		}
+	}
+}
+
+func (d *Diff) add(path []string, value interface{}) {
+	if d.redacts(path) {
+		value = redact(value)
+	}
+	d.Patch = append(d.Patch, Operation{Op: "add", Path: pointer(path), Value: value})
+	d.Summary = append(d.Summary, fmt.Sprintf("%s: added %s", dotted(path), format(value)))
+}
+
+func (d *Diff) remove(path []string, value interface{}) {
+	if d.redacts(path) {
+		value = redact(value)
+	}
+	d.Patch = append(d.Patch, Operation{Op: "remove", Path: pointer(path)})
+	d.Summary = append(d.Summary, fmt.Sprintf("%s: removed %s", dotted(path), format(value)))
+}
+
+func (d *Diff) replace(path []string, old, new interface{}) {
+	if d.redacts(path) {
+		new = redact(new)
+		d.Patch = append(d.Patch, Operation{Op: "replace", Path: pointer(path), Value: new})
+		if _, ok := new.(map[string]interface{}); ok {
+			d.Summary = append(d.Summary, fmt.Sprintf("%s: changed to %s", dotted(path), format(new)))
+		} else {
+			d.Summary = append(d.Summary, fmt.Sprintf("%s: changed", dotted(path)))
+		}
+		return
+	}
+	d.Patch = append(d.Patch, Operation{Op: "replace", Path: pointer(path), Value: new})
+	d.Summary = append(d.Summary, fmt.Sprintf("%s: %s → %s", dotted(path), format(old), format(new)))
+}
+
+// redacts reports whether the value at path is a Secret value
+func (d *Diff) redacts(path []string) bool {
+	return d.secret && len(path) != 0 && secretFields[path[0]]
+}
+
+// redact keeps the keys of value only
+func redact(value interface{}) interface{} {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return redacted
+	}
+	keys := make(map[string]interface{}, len(m))
+	for k := range m {
+		keys[k] = redacted
+	}
+	return keys
+}
+
+// Redact returns a copy of obj with the values of a Secret replaced by their keys,
+// any other object is returned as is, e.g. before posting objects to a handler
+func Redact(obj interface{}) interface{} {
+	if !isSecret(obj) {
+		return obj
+	}
+	var content map[string]interface{}
+	switch object := obj.(type) {
+	case runtime.Unstructured:
+		content = runtime.DeepCopyJSON(object.UnstructuredContent())
+	default:
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil
+		}
+		content = m
+	}
+	for field := range secretFields {
+		if value, ok := content[field]; ok {
+			content[field] = redact(value)
+		}
+	}
+	// kubectl apply keeps the whole Secret in an annotation
+	if annotations, ok, _ := unstructured.NestedStringMap(content, "metadata", "annotations"); ok {
+		if _, ok := annotations[corev1.LastAppliedConfigAnnotation]; ok {
+			annotations[corev1.LastAppliedConfigAnnotation] = redacted
+			unstructured.SetNestedStringMap(content, annotations, "metadata", "annotations")
+		}
+	}
+	return &unstructured.Unstructured{Object: content}
+}
+
+// isSecret reports whether obj is a core/v1 Secret, typed objects
+// coming from informers have no kind set
+func isSecret(obj interface{}) bool {
+	switch object := obj.(type) {
+	case *corev1.Secret:
+		return true
+	case runtime.Unstructured:
+		return object.GetObjectKind().GroupVersionKind() == schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	}
+	return false
+}
+
+func sortedKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pointer returns the JSON Pointer (RFC 6901) of path
+func pointer(path []string) string {
+	var b strings.Builder
+	for _, p := range path {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(p))
+	}
+	return b.String()
+}
+
+// dotted returns path as spec.containers[0].image
+func dotted(path []string) string {
+	var b strings.Builder
+	for _, p := range path {
+		if _, err := strconv.Atoi(p); err == nil {
+			b.WriteString("[" + p + "]")
+			continue
+		}
+		if b.Len() != 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+// format returns value as JSON, without escaping HTML characters such as <
+func format(value interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	b := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	if len(b) > maxValueLength {
+		// cut before a rune rather than in the middle of one
+		n := maxValueLength
+		for n > 0 && !utf8.RuneStart(b[n]) {
+			n--
+		}
+		return string(b[:n]) + "..."
+	}
+	return string(b)
+}
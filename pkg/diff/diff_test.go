@@ -0,0 +1,253 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func deployment(replicas int32, image, resourceVersion string) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "web", ResourceVersion: resourceVersion},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: int32Ptr(replicas),
+			Template: api_v1.PodTemplateSpec{Spec: api_v1.PodSpec{
+				Containers: []api_v1.Container{{Name: "web", Image: image}},
+			}},
+		},
+	}
+}
+
+func secret(data map[string][]byte, stringData map[string]string) *api_v1.Secret {
+	return &api_v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "credentials"},
+		Data:       data,
+		StringData: stringData,
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name        string
+		ignore      []string
+		old, new    interface{}
+		wantPatch   []Operation
+		wantSummary []string
+	}{
+		{
+			"unchanged but ignored fields",
+			nil,
+			deployment(1, "nginx:1.24", "1"),
+			deployment(1, "nginx:1.24", "2"),
+			nil,
+			nil,
+		},
+		{
+			"replaced values",
+			nil,
+			deployment(1, "nginx:1.24", "1"),
+			deployment(3, "nginx:1.25", "2"),
+			[]Operation{
+				{"replace", "/spec/replicas", int64(3)},
+				{"replace", "/spec/template/spec/containers/0/image", "nginx:1.25"},
+			},
+			[]string{
+				"spec.replicas: 1 → 3",
+				`spec.template.spec.containers[0].image: "nginx:1.24" → "nginx:1.25"`,
+			},
+		},
+		{
+			"configured ignore paths",
+			[]string{"spec.replicas"},
+			deployment(1, "nginx:1.24", "1"),
+			deployment(3, "nginx:1.24", "1"),
+			nil,
+			nil,
+		},
+		{
+			"added and removed keys",
+			nil,
+			&unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"a/b": "1", "old": "x"}}}},
+			&unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"a/b": "1", "new": "y"}}}},
+			[]Operation{
+				{"add", "/metadata/labels/new", "y"},
+				{"remove", "/metadata/labels/old", nil},
+			},
+			[]string{
+				`metadata.labels.new: added "y"`,
+				`metadata.labels.old: removed "x"`,
+			},
+		},
+		{
+			"escaped pointer",
+			nil,
+			&unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{"a/b~c": "1"}}}},
+			&unstructured.Unstructured{Object: map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{"a/b~c": "2"}}}},
+			[]Operation{{"replace", "/metadata/annotations/a~1b~0c", "2"}},
+			[]string{`metadata.annotations.a/b~c: "1" → "2"`},
+		},
+		{
+			"resized list",
+			nil,
+			&unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"ports": []interface{}{int64(80)}}}},
+			&unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"ports": []interface{}{int64(80), int64(443)}}}},
+			[]Operation{{"replace", "/spec/ports", []interface{}{int64(80), int64(443)}}},
+			[]string{"spec.ports: [80] → [80,443]"},
+		},
+		{
+			"secret values",
+			nil,
+			secret(map[string][]byte{"password": []byte("hunter2"), "user": []byte("admin")}, nil),
+			secret(map[string][]byte{"password": []byte("hunter3"), "token": []byte("abc")}, map[string]string{"extra": "plain"}),
+			[]Operation{
+				{"replace", "/data/password", redacted},
+				{"add", "/data/token", redacted},
+				{"remove", "/data/user", nil},
+				{"add", "/stringData", map[string]interface{}{"extra": redacted}},
+			},
+			[]string{
+				"data.password: changed",
+				`data.token: added "<redacted>"`,
+				`data.user: removed "<redacted>"`,
+				`stringData: added {"extra":"<redacted>"}`,
+			},
+		},
+		{
+			"unstructured secret",
+			nil,
+			&unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "Secret", "data": map[string]interface{}{"password": "aHVudGVyMg=="}}},
+			&unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "Secret", "data": map[string]interface{}{"password": "aHVudGVyMw=="}}},
+			[]Operation{{"replace", "/data/password", redacted}},
+			[]string{"data.password: changed"},
+		},
+		{
+			"data of another kind",
+			nil,
+			&api_v1.ConfigMap{Data: map[string]string{"mode": "a"}},
+			&api_v1.ConfigMap{Data: map[string]string{"mode": "b"}},
+			[]Operation{{"replace", "/data/mode", "b"}},
+			[]string{`data.mode: "a" → "b"`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := New(test.ignore).Compute(test.old, test.new)
+			if err != nil {
+				t.Fatalf("Compute() = %v", err)
+			}
+			if !reflect.DeepEqual(d.Patch, test.wantPatch) {
+				t.Errorf("Patch = %#v, want %#v", d.Patch, test.wantPatch)
+			}
+			if !reflect.DeepEqual(d.Summary, test.wantSummary) {
+				t.Errorf("Summary = %q, want %q", d.Summary, test.wantSummary)
+			}
+			if d.Empty() != (len(test.wantPatch) == 0) {
+				t.Errorf("Empty() = %v", d.Empty())
+			}
+			for _, line := range d.Summary {
+				if strings.Contains(line, "hunter") || strings.Contains(line, "aHVudGVy") {
+					t.Errorf("Summary leaks a secret value: %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestOperationMarshalJSON(t *testing.T) {
+	tests := []struct {
+		op   Operation
+		want string
+	}{
+		{Operation{"replace", "/spec/replicas", int64(0)}, `{"op":"replace","path":"/spec/replicas","value":0}`},
+		{Operation{"add", "/spec/paused", false}, `{"op":"add","path":"/spec/paused","value":false}`},
+		{Operation{"remove", "/metadata/labels/app", nil}, `{"op":"remove","path":"/metadata/labels/app"}`},
+	}
+	for _, test := range tests {
+		b, err := json.Marshal(test.op)
+		if err != nil {
+			t.Fatalf("Marshal() = %v", err)
+		}
+		if string(b) != test.want {
+			t.Errorf("Marshal(%v) = %s, want %s", test.op, b, test.want)
+		}
+	}
+}
+
+func TestFormatTruncates(t *testing.T) {
+	got := format(strings.Repeat("x", 2*maxValueLength))
+	if len(got) != maxValueLength+len("...") || !strings.HasSuffix(got, "...") {
+		t.Errorf("format() = %q, want %d characters and ...", got, maxValueLength)
+	}
+
+	// the quote and the runes of two and three bytes put a rune across the limit
+	got = format(strings.Repeat("é€", maxValueLength))
+	if !utf8.ValidString(got) || !strings.HasSuffix(got, "...") || len(got) > maxValueLength+len("...") {
+		t.Errorf("format() = %q, want valid UTF-8 of at most %d bytes and ...", got, maxValueLength)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	applied := secret(map[string][]byte{"password": []byte("hunter2")}, nil)
+	applied.Annotations = map[string]string{api_v1.LastAppliedConfigAnnotation: `{"data":{"password":"aHVudGVyMg=="}}`, "team": "a"}
+	unstructuredSecret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "credentials"},
+		"stringData": map[string]interface{}{"token": "abc"},
+	}}
+
+	tests := []struct {
+		name string
+		obj  interface{}
+		want interface{}
+	}{
+		{"nil", nil, nil},
+		{"not a secret", deployment(1, "nginx", "1"), deployment(1, "nginx", "1")},
+		{
+			"secret",
+			applied,
+			map[string]interface{}{
+				"data":     map[string]interface{}{"password": redacted},
+				"metadata": map[string]interface{}{"annotations": map[string]interface{}{api_v1.LastAppliedConfigAnnotation: redacted, "team": "a"}},
+			},
+		},
+		{"unstructured secret", unstructuredSecret, map[string]interface{}{"stringData": map[string]interface{}{"token": redacted}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Redact(test.obj)
+			u, ok := got.(*unstructured.Unstructured)
+			if !ok {
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("Redact() = %v, want %v", got, test.want)
+				}
+				return
+			}
+			for field, want := range test.want.(map[string]interface{}) {
+				value := u.Object[field]
+				if field == "metadata" {
+					value = map[string]interface{}{"annotations": u.Object["metadata"].(map[string]interface{})["annotations"]}
+				}
+				if !reflect.DeepEqual(value, want) {
+					t.Errorf("Redact() %s = %v, want %v", field, value, want)
+				}
+			}
+		})
+	}
+
+	if unstructuredSecret.Object["stringData"].(map[string]interface{})["token"] != "abc" {
+		t.Error("Redact() modified the original object")
+	}
+	if string(applied.Data["password"]) != "hunter2" {
+		t.Error("Redact() modified the original Secret")
+	}
+}
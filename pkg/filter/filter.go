@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Filter is a compiled CEL expression deciding whether an event is watched,
+// it evaluates against object, oldObject, eventType and kind, e.g.
+//
+//	eventType == "update" && object.spec.replicas < oldObject.spec.replicas
+type Filter struct {
+	name    string
+	program cel.Program
+}
+
+var env *cel.Env
+
+func init() {
+	var err error
+	env, err = cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.Variable("oldObject", cel.DynType),
+		cel.Variable("eventType", cel.StringType),
+		cel.Variable("kind", cel.StringType),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create CEL environment: %v", err))
+	}
+}
+
+// New compiles expression, which has to evaluate to a bool
+func New(name, expression string) (*Filter, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression returns %v, want bool", ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{name: name, program: program}, nil
+}
+
+// Name returns the name of the filter
+func (f *Filter) Name() string {
+	return f.name
+}
+
+// Matches evaluates the filter, oldObj is nil unless eventType is update
+func (f *Filter) Matches(eventType, kind string, obj, oldObj interface{}) (bool, error) {
+	object, err := toMap(obj)
+	if err != nil {
+		return false, err
+	}
+	oldObject, err := toMap(oldObj)
+	if err != nil {
+		return false, err
+	}
+
+	out, _, err := f.program.Eval(map[string]interface{}{
+		"object":    object,
+		"oldObject": oldObject,
+		"eventType": eventType,
+		"kind":      kind,
+	})
+	if err != nil {
+		return false, err
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %v, want bool", out.Type())
+	}
+	return matched, nil
+}
+
+// toMap converts obj into the map the expression sees, nil stays null
+func toMap(obj interface{}) (interface{}, error) {
+	switch object := obj.(type) {
+	case nil:
+		return nil, nil
+	case runtime.Unstructured:
+		return object.UnstructuredContent(), nil
+	default:
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %T: %v", obj, err)
+		}
+		return m, nil
+	}
+}
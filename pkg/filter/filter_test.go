@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{"bool", `kind == "Pod"`, false},
+		{"dynamic", `object.spec.paused`, false},
+		{"syntax error", `kind ==`, true},
+		{"unknown variable", `namespace == "default"`, true},
+		{"not a bool", `kind + "s"`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := New(test.name, test.expression)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("New(%q) error = %v, wantErr %v", test.expression, err, test.wantErr)
+			}
+			if err == nil && f.Name() != test.name {
+				t.Errorf("Name() = %q, want %q", f.Name(), test.name)
+			}
+		})
+	}
+}
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func deployment(replicas int32) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "web", Labels: map[string]string{"app": "web"}},
+		Spec:       apps_v1.DeploymentSpec{Replicas: int32Ptr(replicas)},
+	}
+}
+
+func TestMatches(t *testing.T) {
+	restarting := &api_v1.Pod{Status: api_v1.PodStatus{ContainerStatuses: []api_v1.ContainerStatus{{Name: "web", RestartCount: 6}}}}
+
+	tests := []struct {
+		name       string
+		expression string
+		eventType  string
+		kind       string
+		obj        interface{}
+		oldObj     interface{}
+		want       bool
+		wantErr    bool
+	}{
+		{"kind", `kind == "Deployment"`, "create", "Deployment", deployment(1), nil, true, false},
+		{"event type", `eventType == "delete"`, "create", "Deployment", deployment(1), nil, false, false},
+		{"scaled down", `eventType == "update" && object.spec.replicas < oldObject.spec.replicas`, "update", "Deployment", deployment(1), deployment(3), true, false},
+		{"scaled up", `eventType == "update" && object.spec.replicas < oldObject.spec.replicas`, "update", "Deployment", deployment(3), deployment(1), false, false},
+		{"labels", `object.metadata.labels.app == "web"`, "create", "Deployment", deployment(1), nil, true, false},
+		{"macro", `object.status.containerStatuses.exists(c, c.restartCount > 5)`, "update", "Pod", restarting, nil, true, false},
+		{
+			"unstructured",
+			`object.spec.size > 2`,
+			"create",
+			"Database",
+			&unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"size": int64(3)}}},
+			nil,
+			true,
+			false,
+		},
+		{"missing field", `object.spec.missing == 1`, "create", "Deployment", deployment(1), nil, false, true},
+		{"old object of a create", `oldObject.spec.replicas == 1`, "create", "Deployment", deployment(1), nil, false, true},
+		{"not a bool at runtime", `object.metadata.name`, "create", "Deployment", deployment(1), nil, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := New(test.name, test.expression)
+			if err != nil {
+				t.Fatalf("New(%q) = %v", test.expression, err)
+			}
+			got, err := f.Matches(test.eventType, test.kind, test.obj, test.oldObj)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Matches() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("Matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/metrics"
+	"github.com/walk1ng/k8swatch/pkg/utils"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// partialDeliveryTTL bounds how long the handlers an event was delivered to are
+// remembered, longer than the retries of the event
+const partialDeliveryTTL = time.Hour
+
+// sinkQueueSize bounds the events waiting for a handler, every handler
+// delivers on its own so that a slow handler does not hold the others back
+const sinkQueueSize = 1000
+
+// Composite handler implement
+// Fan out events to several handlers according to their routing rules,
+// every handler has its own queue and worker, the error of Handle lists the
+// handlers which failed so the caller retries the event for those only
+type Composite struct {
+	sinks []*sink
+
+	start sync.Once
+	// workers are the workers of the sinks, done once their queues are drained
+	workers sync.WaitGroup
+
+	mu sync.Mutex
+	// closed once flushed, later events are rejected
+	closed bool
+	// partial holds the events some handlers failed to deliver by id
+	partial map[string]*partialDelivery
+}
+
+// sink is one handler of a Composite
+type sink struct {
+	name    string
+	handler EventHandler
+	rules   []rule
+	logger  *logrus.Entry
+	queue   chan delivery
+}
+
+// delivery is an event waiting in the queue of a handler, its
+// outcome is sent on result
+type delivery struct {
+	ctx    context.Context
+	event  Event
+	result chan<- error
+}
+
+// partialDelivery is an event which some handlers failed to deliver,
+// settled are the handlers which delivered it or rejected it for good
+type partialDelivery struct {
+	settled map[string]bool
+	at      time.Time
+}
+
+// rule is the compiled form of config.Rule
+type rule struct {
+	kinds      []string
+	namespaces []string
+	eventTypes []string
+	selector   labels.Selector
+	owners     []string
+}
+
+// Add registers a handler under name, events are routed to it when they
+// match any of the rules, or always when there is no rule
+func (m *Composite) Add(name string, handler EventHandler, rules []config.Rule) error {
+	s := &sink{
+		name:    name,
+		handler: handler,
+		logger:  logrus.WithField("pkg", "k8swatch-"+name),
+		queue:   make(chan delivery, sinkQueueSize),
+	}
+	for i, r := range rules {
+		selector, err := labels.Parse(r.Labels)
+		if err != nil {
+			return fmt.Errorf("invalid labels in rule %d of %s handler: %v", i, name, err)
+		}
+		for _, ns := range r.Namespaces {
+			if _, err := path.Match(ns, ""); err != nil {
+				return fmt.Errorf("invalid namespace pattern %q in rule %d of %s handler: %v", ns, i, name, err)
+			}
+		}
+		for _, owner := range r.Owners {
+			if _, err := path.Match(owner, ""); err != nil {
+				return fmt.Errorf("invalid owner pattern %q in rule %d of %s handler: %v", owner, i, name, err)
+			}
+		}
+		s.rules = append(s.rules, rule{
+			kinds:      r.Kinds,
+			namespaces: r.Namespaces,
+			eventTypes: r.EventTypes,
+			selector:   selector,
+			owners:     r.Owners,
+		})
+	}
+	m.sinks = append(m.sinks, s)
+	return nil
+}
+
+// Len returns the number of registered handlers
+func (m *Composite) Len() int {
+	return len(m.sinks)
+}
+
+// Init initializes every registered handler and starts their workers
+func (m *Composite) Init(c *config.Config) error {
+	for _, s := range m.sinks {
+		if err := s.handler.Init(c); err != nil {
+			return fmt.Errorf("%s handler: %v", s.name, err)
+		}
+	}
+	m.run()
+	return nil
+}
+
+// run starts the worker of every handler once
+func (m *Composite) run() {
+	m.start.Do(func() {
+		for _, s := range m.sinks {
+			m.workers.Add(1)
+			go func(s *sink) {
+				defer m.workers.Done()
+				for d := range s.queue {
+					if err := d.ctx.Err(); err != nil {
+						d.result <- err
+						continue
+					}
+					d.result <- s.deliver(d.ctx, d.event)
+				}
+			}(s)
+		}
+	})
+}
+
+// Handle delivers e to the handlers it matches, each through its own queue, and
+// waits for them until ctx is done, the error lists the handlers which failed,
+// it is Permanent when none of them may succeed later, an event is only
+// delivered again to the handlers which failed it
+func (m *Composite) Handle(ctx context.Context, e Event) error {
+	m.run()
+
+	set := labels.Set(utils.GetObjectMetaData(e.Object).Labels)
+	results := map[string]chan error{}
+	var failed []string
+	retryable := false
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return Permanent(fmt.Errorf("handlers are shut down"))
+	}
+	m.forgetPartial()
+	// the handlers settled by the earlier attempts, copied as the map is updated unlocked
+	settled := map[string]bool{}
+	at := time.Now()
+	if p, ok := m.partial[e.ID]; ok {
+		for name := range p.settled {
+			settled[name] = true
+		}
+		at = p.at
+	}
+	for _, s := range m.sinks {
+		if settled[s.name] || !s.matches(e.Kind, e.Namespace, e.Type, set, e.Owner) {
+			continue
+		}
+		result := make(chan error, 1)
+		select {
+		case s.queue <- delivery{ctx: ctx, event: e, result: result}:
+			results[s.name] = result
+		default:
+			s.logger.Warnf("Queue full, deferring %s event of %s %s", e.Type, e.Kind, e.Name)
+			failed = append(failed, s.name+" (queue full)")
+			retryable = true
+		}
+	}
+	m.mu.Unlock()
+
+	for _, s := range m.sinks {
+		result, ok := results[s.name]
+		if !ok {
+			continue
+		}
+		var err error
+		select {
+		case err = <-result:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		switch {
+		case err == nil:
+			settled[s.name] = true
+		case IsPermanent(err):
+			s.logger.Errorf("Dropping %s event of %s %s: %v", e.Type, e.Kind, e.Name, err)
+			settled[s.name] = true
+			failed = append(failed, fmt.Sprintf("%s (%v)", s.name, err))
+		default:
+			failed = append(failed, fmt.Sprintf("%s (%v)", s.name, err))
+			retryable = true
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !retryable {
+		delete(m.partial, e.ID)
+		if len(failed) != 0 {
+			return Permanent(fmt.Errorf("handlers failed: %s", strings.Join(failed, ", ")))
+		}
+		return nil
+	}
+	if m.partial == nil {
+		m.partial = map[string]*partialDelivery{}
+	}
+	m.partial[e.ID] = &partialDelivery{settled: settled, at: at}
+	return fmt.Errorf("handlers failed: %s", strings.Join(failed, ", "))
+}
+
+// forgetPartial drops the partial deliveries of events which are not retried anymore
+func (m *Composite) forgetPartial() {
+	for id, p := range m.partial {
+		if time.Since(p.at) > partialDeliveryTTL {
+			delete(m.partial, id)
+		}
+	}
+}
+
+// Flush rejects later events, waits for the workers of the handlers to
+// finish their deliveries, giving up when ctx is done, then flushes every handler
+func (m *Composite) Flush(ctx context.Context) error {
+	m.run()
+
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		for _, s := range m.sinks {
+			close(s.queue)
+		}
+	}
+	m.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		m.workers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to deliver queued events: %v", ctx.Err())
+	}
+
+	var failed []string
+	for _, s := range m.sinks {
+		if f, ok := s.handler.(Flusher); ok {
+			if err := f.Flush(ctx); err != nil {
+				failed = append(failed, fmt.Sprintf("%s (%v)", s.name, err))
+			}
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("failed to flush handlers: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Close closes every handler
+func (m *Composite) Close() error {
+	var failed []string
+	for _, s := range m.sinks {
+		if c, ok := s.handler.(Closer); ok {
+			if err := c.Close(); err != nil {
+				failed = append(failed, fmt.Sprintf("%s (%v)", s.name, err))
+			}
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("failed to close handlers: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// deliver runs one delivery, a panicking handler only fails its own delivery
+// and a handler ignoring ctx is left behind when ctx is done
+func (s *sink) deliver(ctx context.Context, e Event) error {
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- fmt.Errorf("recovered from panic: %v", r)
+			}
+		}()
+		result <- s.handler.Handle(ctx, e)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	metrics.HandlerDuration.WithLabelValues(s.name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		s.logger.Debugf("Failed to deliver %s event of %s %s: %v", e.Type, e.Kind, e.Name, err)
+		metrics.HandlerErrors.WithLabelValues(s.name).Inc()
+	}
+	return err
+}
+
+func (s *sink) matches(kind, namespace, eventType string, set labels.Set, owner *Owner) bool {
+	if len(s.rules) == 0 {
+		return true
+	}
+	for _, r := range s.rules {
+		if r.matches(kind, namespace, eventType, set, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether every condition of the rule holds,
+// an empty condition always holds, owners never hold for an object without owner
+func (r rule) matches(kind, namespace, eventType string, set labels.Set, owner *Owner) bool {
+	if len(r.kinds) != 0 && !utils.ContainsFold(r.kinds, kind) {
+		return false
+	}
+	if len(r.eventTypes) != 0 && !utils.ContainsFold(r.eventTypes, eventType) {
+		return false
+	}
+	if len(r.namespaces) != 0 && !utils.MatchesAny(r.namespaces, namespace) {
+		return false
+	}
+	if len(r.owners) != 0 && (owner == nil || !utils.MatchesAny(r.owners, owner.Kind+"/"+owner.Name)) {
+		return false
+	}
+	return r.selector.Matches(set)
+}
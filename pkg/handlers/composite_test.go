@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// countingHandler counts its deliveries by event id, failing the first
+// ones with err, and blocks while block is open
+type countingHandler struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	fails   int
+	err     error
+	block   chan struct{}
+	blocked int
+}
+
+func (h *countingHandler) Init(c *config.Config) error { return nil }
+
+func (h *countingHandler) Handle(ctx context.Context, e Event) error {
+	if h.block != nil {
+		h.mu.Lock()
+		h.blocked++
+		h.mu.Unlock()
+		<-h.block
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.calls == nil {
+		h.calls = map[string]int{}
+	}
+	h.calls[e.ID]++
+	if h.calls[e.ID] <= h.fails {
+		return h.err
+	}
+	return nil
+}
+
+func (h *countingHandler) waiting() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.blocked != 0
+}
+
+func (h *countingHandler) count(id string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls[id]
+}
+
+// eventually fails unless cond holds within 5 seconds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("%s did not happen within 5 seconds", what)
+}
+
+func flush(t *testing.T, m *Composite) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Flush(ctx); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+}
+
+func TestCompositeHandle(t *testing.T) {
+	unavailable := errors.New("unavailable")
+	rejected := Permanent(errors.New("bad request"))
+
+	tests := []struct {
+		name          string
+		handlers      []*countingHandler
+		wantErr       bool
+		wantPermanent bool
+		// wantCalls are the deliveries of every handler once the event is handled again
+		wantCalls []int
+	}{
+		{"delivered", []*countingHandler{{}, {}}, false, false, []int{1, 1}},
+		{"one failing", []*countingHandler{{fails: 1, err: unavailable}, {}}, true, false, []int{2, 1}},
+		{"every failing", []*countingHandler{{fails: 1, err: unavailable}, {fails: 1, err: unavailable}}, true, false, []int{2, 2}},
+		{"one rejecting", []*countingHandler{{fails: 1, err: rejected}, {}}, true, true, []int{1, 1}},
+		{"one rejecting one failing", []*countingHandler{{fails: 1, err: rejected}, {fails: 1, err: unavailable}}, true, false, []int{1, 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &Composite{}
+			for i, h := range test.handlers {
+				m.Add(fmt.Sprint("sink", i), h, nil)
+			}
+			e := Event{ID: "1"}
+			err := m.Handle(context.Background(), e)
+			if (err != nil) != test.wantErr || IsPermanent(err) != test.wantPermanent {
+				t.Fatalf("Handle() = %v, wantErr %v, wantPermanent %v", err, test.wantErr, test.wantPermanent)
+			}
+			// the caller retries the event unless the error is Permanent
+			if err != nil && !IsPermanent(err) {
+				if err := m.Handle(context.Background(), e); err != nil {
+					t.Fatalf("Handle() retry = %v", err)
+				}
+			}
+			for i, h := range test.handlers {
+				if got := h.count("1"); got != test.wantCalls[i] {
+					t.Errorf("sink%d delivered %d times, want %d", i, got, test.wantCalls[i])
+				}
+			}
+			if m.partial["1"] != nil {
+				t.Errorf("partial delivery is still remembered")
+			}
+			flush(t, m)
+		})
+	}
+}
+
+func TestCompositeQueueFull(t *testing.T) {
+	slow := &countingHandler{block: make(chan struct{})}
+	fast := &countingHandler{}
+	m := &Composite{}
+	m.Add("slow", slow, nil)
+	m.Add("fast", fast, nil)
+
+	// the worker of slow blocks on the first event
+	go m.Handle(context.Background(), Event{ID: "first"})
+	eventually(t, "slow picking the first event", slow.waiting)
+
+	// callers giving up leave their events in the queue of slow
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < sinkQueueSize; i++ {
+		if err := m.Handle(canceled, Event{ID: fmt.Sprint(i)}); err == nil {
+			t.Fatalf("Handle(%d) = nil with a canceled context, want an error", i)
+		}
+	}
+
+	e := Event{ID: "partial"}
+	err := m.Handle(context.Background(), e)
+	if err == nil || IsPermanent(err) || !strings.Contains(err.Error(), "slow (queue full)") || strings.Contains(err.Error(), "fast") {
+		t.Fatalf("Handle() = %v, want a retryable error naming slow only", err)
+	}
+	// fast is not held back by slow
+	if got := fast.count("partial"); got != 1 {
+		t.Errorf("fast got the event %d times, want once", got)
+	}
+
+	close(slow.block)
+	eventually(t, "slow draining its queue", func() bool { return len(m.sinks[0].queue) == 0 })
+	if err := m.Handle(context.Background(), e); err != nil {
+		t.Fatalf("Handle() retry = %v", err)
+	}
+	flush(t, m)
+
+	if got := fast.count("partial"); got != 1 {
+		t.Errorf("fast got the event %d times, want once", got)
+	}
+	if got := slow.count("partial"); got != 1 {
+		t.Errorf("slow got the event %d times, want once", got)
+	}
+}
+
+func TestCompositeFlush(t *testing.T) {
+	h := &countingHandler{}
+	m := &Composite{}
+	m.Add("sink", h, nil)
+	for i := 0; i < 10; i++ {
+		if err := m.Handle(context.Background(), Event{ID: fmt.Sprint(i)}); err != nil {
+			t.Fatalf("Handle() = %v", err)
+		}
+	}
+	flush(t, m)
+	for i := 0; i < 10; i++ {
+		if got := h.count(fmt.Sprint(i)); got != 1 {
+			t.Errorf("event %d delivered %d times before Flush returned, want once", i, got)
+		}
+	}
+	if err := m.Handle(context.Background(), Event{ID: "late"}); !IsPermanent(err) {
+		t.Errorf("Handle() after Flush = %v, want a Permanent error", err)
+	}
+
+	blocked := &countingHandler{block: make(chan struct{})}
+	defer close(blocked.block)
+	m = &Composite{}
+	m.Add("sink", blocked, nil)
+	go m.Handle(context.Background(), Event{ID: "1"})
+	eventually(t, "the handler picking the event", blocked.waiting)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Flush(ctx); err == nil {
+		t.Errorf("Flush() = nil with a blocked handler, want an error")
+	}
+}
+
+func TestCompositeRouting(t *testing.T) {
+	pod := testPod("web")
+	pod.Labels = map[string]string{"app": "web"}
+	owner := &Owner{Kind: "Deployment", Namespace: "default", Name: "web"}
+
+	tests := []struct {
+		name  string
+		rules []config.Rule
+		event Event
+		want  bool
+	}{
+		{"no rule", nil, Event{Kind: "Pod"}, true},
+		{"kind", []config.Rule{{Kinds: []string{"pod"}}}, Event{Kind: "Pod"}, true},
+		{"other kind", []config.Rule{{Kinds: []string{"Deployment"}}}, Event{Kind: "Pod"}, false},
+		{"namespace glob", []config.Rule{{Namespaces: []string{"prod-*"}}}, Event{Namespace: "prod-eu"}, true},
+		{"other namespace", []config.Rule{{Namespaces: []string{"prod-*"}}}, Event{Namespace: "dev"}, false},
+		{"event type", []config.Rule{{EventTypes: []string{"delete"}}}, Event{Type: "create"}, false},
+		{"labels", []config.Rule{{Labels: "app=web"}}, Event{Object: pod}, true},
+		{"other labels", []config.Rule{{Labels: "app!=web"}}, Event{Object: pod}, false},
+		{"owner", []config.Rule{{Owners: []string{"Deployment/*"}}}, Event{Owner: owner}, true},
+		{"no owner", []config.Rule{{Owners: []string{"Deployment/*"}}}, Event{}, false},
+		{"any rule", []config.Rule{{Kinds: []string{"Node"}}, {Kinds: []string{"Pod"}}}, Event{Kind: "Pod"}, true},
+		{"every condition", []config.Rule{{Kinds: []string{"Pod"}, Namespaces: []string{"kube-system"}}}, Event{Kind: "Pod", Namespace: "default"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &Composite{}
+			if err := m.Add("sink", &countingHandler{}, test.rules); err != nil {
+				t.Fatalf("Add() = %v", err)
+			}
+			e := test.event
+			set := labels.Set(objectLabels(e.Object))
+			if got := m.sinks[0].matches(e.Kind, e.Namespace, e.Type, set, e.Owner); got != test.want {
+				t.Errorf("matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	for _, rule := range []config.Rule{{Labels: "app in ("}, {Namespaces: []string{"["}}, {Owners: []string{"["}}} {
+		if err := (&Composite{}).Add("sink", &countingHandler{}, []config.Rule{rule}); err == nil {
+			t.Errorf("Add(%+v) = nil, want an error", rule)
+		}
+	}
+}
+
+func objectLabels(obj interface{}) map[string]string {
+	if o, ok := obj.(meta_v1.Object); ok {
+		return o.GetLabels()
+	}
+	return nil
+}
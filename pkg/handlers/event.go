@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/diff"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Event is the envelope of an event handed to an EventHandler
+type Event struct {
+	// ID identifies the event across its delivery attempts
+	ID string
+	// Type is create, update, delete or notification
+	Type      string
+	Kind      string
+	GVR       schema.GroupVersionResource
+	Namespace string
+	Name      string
+	// Object is the last known state of a deleted object,
+	// OldObject is only set for updates
+	Object    interface{}
+	OldObject interface{}
+	// Diff holds the changes of an update, if computed
+	Diff  *diff.Diff
+	Owner *Owner
+	// Notification is only set for notifications, about Object
+	Notification *Notification
+	// Cluster names the watched cluster, if configured
+	Cluster string
+	// Timestamp is when the event was observed
+	Timestamp time.Time
+}
+
+// EventHandler can be implemented by any handler, a failed delivery
+// returns an error and the event is delivered again later, unless the error is Permanent
+type EventHandler interface {
+	Init(c *config.Config) error
+	Handle(ctx context.Context, e Event) error
+}
+
+// Adapt turns a Handler into an EventHandler, a Handler cannot report failures
+// so its events are never retried, and it only gets the objects of events,
+// notifications, which have no counterpart in Handler, are left out
+func Adapt(h Handler) EventHandler {
+	return legacyHandler{h}
+}
+
+type legacyHandler struct {
+	Handler
+}
+
+// Handle calls the method of the event type
+func (h legacyHandler) Handle(ctx context.Context, e Event) error {
+	switch e.Type {
+	case "create":
+		h.ObjCreated(e.Object)
+	case "update":
+		h.ObjUpdated(e.OldObject, e.Object)
+	case "delete":
+		h.ObjDeleted(e.Object)
+	}
+	return nil
+}
+
+// Flush flushes the adapted handler if it implements Flusher
+func (h legacyHandler) Flush(ctx context.Context) error {
+	if f, ok := h.Handler.(Flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// Close closes the adapted handler if it implements Closer
+func (h legacyHandler) Close() error {
+	if c, ok := h.Handler.(Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// permanentError is a failure that retrying does not fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as not worth a retry, e.g. a request the receiver rejected
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err was marked by Permanent
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
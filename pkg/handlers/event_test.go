@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// recordingHandler is a Handler recording its calls
+type recordingHandler struct {
+	calls []string
+}
+
+func (h *recordingHandler) Init(c *config.Config) error { return nil }
+
+func (h *recordingHandler) ObjCreated(obj interface{}) {
+	h.calls = append(h.calls, "created "+podName(obj))
+}
+
+func (h *recordingHandler) ObjUpdated(old, new interface{}) {
+	h.calls = append(h.calls, fmt.Sprintf("updated %s to %s", podName(old), podName(new)))
+}
+
+func (h *recordingHandler) ObjDeleted(obj interface{}) {
+	h.calls = append(h.calls, "deleted "+podName(obj))
+}
+
+func podName(obj interface{}) string {
+	if pod, ok := obj.(*api_v1.Pod); ok {
+		return pod.Name
+	}
+	return "<nil>"
+}
+
+func testPod(name string) *api_v1.Pod {
+	return &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: name}}
+}
+
+func TestAdapt(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  []string
+	}{
+		{"create", Event{Type: "create", Object: testPod("a")}, []string{"created a"}},
+		{"update", Event{Type: "update", Object: testPod("b"), OldObject: testPod("a")}, []string{"updated a to b"}},
+		{"delete", Event{Type: "delete", Object: testPod("a")}, []string{"deleted a"}},
+		{"notification", Event{Type: "notification", Object: testPod("a"), Notification: &Notification{Reason: "BackOff"}}, nil},
+		{"unknown", Event{Type: "other", Object: testPod("a")}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := &recordingHandler{}
+			if err := Adapt(h).Handle(context.Background(), test.event); err != nil {
+				t.Fatalf("Handle() = %v, want nil", err)
+			}
+			if fmt.Sprint(h.calls) != fmt.Sprint(test.want) {
+				t.Errorf("calls = %v, want %v", h.calls, test.want)
+			}
+		})
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	err := errors.New("rejected")
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", err, false},
+		{"permanent", Permanent(err), true},
+		{"wrapped", fmt.Errorf("post: %w", Permanent(err)), true},
+	}
+	for _, test := range tests {
+		if got := IsPermanent(test.err); got != test.want {
+			t.Errorf("%s: IsPermanent() = %v, want %v", test.name, got, test.want)
+		}
+	}
+	if Permanent(nil) != nil {
+		t.Errorf("Permanent(nil) != nil")
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/diff"
+	"github.com/walk1ng/k8swatch/pkg/utils"
+)
+
+// eventRecord is the printable form of an event
+type eventRecord struct {
+	Kind            string     `json:"kind"`
+	Namespace       string     `json:"namespace,omitempty"`
+	Name            string     `json:"name"`
+	EventType       string     `json:"eventType"`
+	Timestamp       string     `json:"timestamp"`
+	UID             string     `json:"uid"`
+	ResourceVersion string     `json:"resourceVersion"`
+	Diff            *diff.Diff `json:"diff,omitempty"`
+	Severity        string     `json:"severity,omitempty"`
+	Reason          string     `json:"reason,omitempty"`
+	Message         string     `json:"message,omitempty"`
+	Owner           *Owner     `json:"owner,omitempty"`
+}
+
+// newEventRecord returns the record of e, with the changes of an update
+// or the reason and message of a notification
+func newEventRecord(e Event) eventRecord {
+	objMeta := utils.GetObjectMetaData(e.Object)
+	timestamp := e.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	r := eventRecord{
+		Kind:            e.Kind,
+		Namespace:       e.Namespace,
+		Name:            e.Name,
+		EventType:       e.Type,
+		Timestamp:       timestamp.UTC().Format(time.RFC3339),
+		UID:             string(objMeta.UID),
+		ResourceVersion: objMeta.ResourceVersion,
+		Diff:            e.Diff,
+		Owner:           e.Owner,
+	}
+	if n := e.Notification; n != nil {
+		r.Severity = n.Severity
+		r.Reason = n.Reason
+		r.Message = n.Message
+	}
+	return r
+}
+
+// formatter renders an event record as a single line
+type formatter func(r eventRecord, color bool) string
+
+var formatters = map[string]formatter{
+	"json":   formatJSON,
+	"logfmt": formatLogfmt,
+	"table":  formatTable,
+}
+
+// formatJSON renders the record as a JSON line, ready for jq
+func formatJSON(r eventRecord, color bool) string {
+	b, err := json.Marshal(r)
+	if err != nil {
+		// not expected, the values of the diff were decoded from JSON
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+	return string(b)
+}
+
+// formatLogfmt renders the record as logfmt key=value pairs
+func formatLogfmt(r eventRecord, color bool) string {
+	pairs := []struct {
+		key, value string
+	}{
+		{"time", r.Timestamp},
+		{"type", r.EventType},
+		{"kind", r.Kind},
+		{"namespace", r.Namespace},
+		{"name", r.Name},
+		{"uid", r.UID},
+		{"resourceVersion", r.ResourceVersion},
+	}
+	if r.Owner != nil {
+		pairs = append(pairs, struct {
+			key, value string
+		}{"owner", r.Owner.Kind + "/" + r.Owner.Name})
+	}
+	if !r.Diff.Empty() {
+		pairs = append(pairs, struct {
+			key, value string
+		}{"changes", strings.Join(r.Diff.Summary, "; ")})
+	}
+	if r.Message != "" {
+		pairs = append(pairs, []struct {
+			key, value string
+		}{
+			{"severity", r.Severity},
+			{"reason", r.Reason},
+			{"message", r.Message},
+		}...)
+	}
+
+	fields := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		fields = append(fields, p.key+"="+logfmtValue(p.value))
+	}
+	return strings.Join(fields, " ")
+}
+
+// logfmtValue quotes values that would break key=value parsing
+func logfmtValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " =\"\t\n") {
+		return fmt.Sprintf("%q", v)
+	}
+	return v
+}
+
+const tableRow = "%-20s  %-8s  %-16s  %-20s  %-40s  %-36s  %-36s  %s"
+
+func tableHeader() string {
+	return fmt.Sprintf(tableRow, "TIME", "TYPE", "KIND", "NAMESPACE", "NAME", "OWNER", "UID", "RESOURCEVERSION")
+}
+
+// eventColors maps event types and severities to ANSI colors for the table format
+var eventColors = map[string]string{
+	"create":         "\x1b[32m",
+	"update":         "\x1b[33m",
+	"delete":         "\x1b[31m",
+	SeverityInfo:     "\x1b[36m",
+	SeverityWarning:  "\x1b[33m",
+	SeverityCritical: "\x1b[31m",
+}
+
+// colorKey returns the key of the color of a record
+func colorKey(r eventRecord) string {
+	if r.Severity != "" {
+		return r.Severity
+	}
+	return r.EventType
+}
+
+// formatTable renders the record as an aligned table row
+func formatTable(r eventRecord, color bool) string {
+	// notifications show their severity, it fits the column
+	eventType := r.EventType
+	if r.Severity != "" {
+		eventType = r.Severity
+	}
+	eventType = fmt.Sprintf("%-8s", eventType)
+	if c, ok := eventColors[colorKey(r)]; ok && color {
+		eventType = c + eventType + "\x1b[0m"
+	}
+	owner := "-"
+	if r.Owner != nil {
+		owner = r.Owner.Kind + "/" + r.Owner.Name
+	}
+	row := fmt.Sprintf("%-20s  %s  %-16s  %-20s  %-40s  %-36s  %-36s  %s",
+		r.Timestamp, eventType, r.Kind, r.Namespace, r.Name, owner, r.UID, r.ResourceVersion)
+	if r.Message != "" {
+		row += "  " + r.Message
+	}
+	return row
+}
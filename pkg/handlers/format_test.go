@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/diff"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewEventRecord(t *testing.T) {
+	pod := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "web", UID: "u1", ResourceVersion: "7"}}
+	observed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	r := newEventRecord(Event{
+		Type:         "notification",
+		Kind:         "Pod",
+		Namespace:    "default",
+		Name:         "web",
+		Object:       pod,
+		Owner:        &Owner{Kind: "Deployment", Namespace: "default", Name: "web"},
+		Notification: &Notification{Reason: "OOMKilled", Message: "container web OOMKilled", Severity: SeverityCritical},
+		Timestamp:    observed,
+	})
+	want := eventRecord{
+		Kind:            "Pod",
+		Namespace:       "default",
+		Name:            "web",
+		EventType:       "notification",
+		Timestamp:       "2023-12-31T23:00:00Z",
+		UID:             "u1",
+		ResourceVersion: "7",
+		Severity:        SeverityCritical,
+		Reason:          "OOMKilled",
+		Message:         "container web OOMKilled",
+		Owner:           &Owner{Kind: "Deployment", Namespace: "default", Name: "web"},
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("newEventRecord() = %+v, want %+v", r, want)
+	}
+}
+
+func TestFormatters(t *testing.T) {
+	created := eventRecord{Kind: "Pod", Namespace: "default", Name: "web", EventType: "create", Timestamp: "2024-01-01T00:00:00Z", UID: "u1", ResourceVersion: "7"}
+	updated := created
+	updated.EventType = "update"
+	updated.Diff = &diff.Diff{
+		Patch: []diff.Operation{
+			{Op: "replace", Path: "/spec/replicas", Value: 2},
+			{Op: "add", Path: "/metadata/labels/app", Value: "web"},
+		},
+		Summary: []string{"spec.replicas: 1 → 2", "metadata.labels.app: added"},
+	}
+	updated.Owner = &Owner{Kind: "Deployment", Namespace: "default", Name: "web"}
+	notified := created
+	notified.EventType = "notification"
+	notified.Severity = SeverityWarning
+	notified.Reason = "Evicted"
+	notified.Message = "Evicted: low on memory"
+
+	tests := []struct {
+		name   string
+		format string
+		record eventRecord
+		color  bool
+		want   string
+	}{
+		{"logfmt", "logfmt", created, false, "time=2024-01-01T00:00:00Z type=create kind=Pod namespace=default name=web uid=u1 resourceVersion=7"},
+		{
+			"logfmt changes",
+			"logfmt",
+			updated,
+			false,
+			`time=2024-01-01T00:00:00Z type=update kind=Pod namespace=default name=web uid=u1 resourceVersion=7 owner=Deployment/web changes="spec.replicas: 1 → 2; metadata.labels.app: added"`,
+		},
+		{
+			"logfmt notification",
+			"logfmt",
+			notified,
+			false,
+			`time=2024-01-01T00:00:00Z type=notification kind=Pod namespace=default name=web uid=u1 resourceVersion=7 severity=warning reason=Evicted message="Evicted: low on memory"`,
+		},
+		{"table", "table", created, false, "2024-01-01T00:00:00Z  create    Pod               default               web                                       -                                     u1                                    7"},
+		{"table color", "table", created, true, "2024-01-01T00:00:00Z  \x1b[32mcreate  \x1b[0m  Pod"},
+		{"table notification", "table", notified, false, "  Evicted: low on memory"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := formatters[test.format](test.record, test.color)
+			if test.format == "table" && !strings.HasPrefix(got, test.want) && !strings.HasSuffix(got, test.want) {
+				t.Errorf("%s = %q, want it to start or end with %q", test.format, got, test.want)
+			}
+			if test.format != "table" && got != test.want {
+				t.Errorf("%s = %q, want %q", test.format, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	r := eventRecord{Kind: "Pod", Name: "web", EventType: "delete", Timestamp: "2024-01-01T00:00:00Z"}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(formatJSON(r, false)), &got); err != nil {
+		t.Fatalf("formatJSON() is not JSON: %v", err)
+	}
+	want := map[string]interface{}{"kind": "Pod", "name": "web", "eventType": "delete", "timestamp": "2024-01-01T00:00:00Z", "uid": "", "resourceVersion": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("formatJSON() = %v, want %v", got, want)
+	}
+}
@@ -1,40 +1,145 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
 	"github.com/walk1ng/k8swatch/pkg/config"
 )
 
-// Handler interface can be implemented by any handler
-// The handler methods are used to process events
+// Handler interface is the former interface of handlers, which cannot report
+// failures, wrap a Handler with Adapt to use it as an EventHandler
 type Handler interface {
 	Init(c *config.Config) error
 	ObjCreated(obj interface{})
-	ObjUpdated(obj interface{})
-	ObjDeleted(old, new interface{})
+	ObjUpdated(old, new interface{})
+	ObjDeleted(obj interface{})
+}
+
+// Flusher is implemented by handlers buffering events,
+// Flush delivers the buffered events or gives up when ctx is done
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Closer is implemented by handlers holding resources, such as an output file
+type Closer interface {
+	Close() error
+}
+
+// Shutdown flushes then closes h when it implements Flusher or Closer,
+// no event must be handed to h anymore
+func Shutdown(ctx context.Context, h EventHandler) error {
+	if f, ok := h.(Flusher); ok {
+		if err := f.Flush(ctx); err != nil {
+			return err
+		}
+	}
+	if c, ok := h.(Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 // Default handler implement
-// Print event with json format
+// Print event with json format, or logfmt/table if configured
 type Default struct {
+	mu  sync.Mutex
+	out io.Writer
+	// file is the output file, if any, closed by Close
+	file   *os.File
+	format string
+	color  bool
+	header bool
 }
 
 // Init initializes handler configuration
-// do nothing for Default handler
+// select the output stream or file and format for Default handler
 func (d *Default) Init(c *config.Config) error {
+	out := os.Stdout
+	switch c.Handler.Default.Output {
+	case "", "stdout":
+	case "stderr":
+		out = os.Stderr
+	default:
+		f, err := os.OpenFile(c.Handler.Default.Output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open output of default handler: %v", err)
+		}
+		out = f
+	}
+
+	format := c.Handler.Default.Format
+	if format == "" {
+		format = "json"
+	}
+	if _, ok := formatters[format]; !ok {
+		return fmt.Errorf("unknown format %q for default handler", format)
+	}
+
+	d.out = out
+	if out != os.Stdout && out != os.Stderr {
+		d.file = out
+	}
+	d.format = format
+	d.color = isTerminal(out)
 	return nil
 }
 
-// ObjCreated handle object created event
-func (d *Default) ObjCreated(obj interface{}) {
-
+// Handle prints e
+func (d *Default) Handle(ctx context.Context, e Event) error {
+	return d.print(newEventRecord(e))
 }
 
-// ObjUpdated handle object updated event
-func (d *Default) ObjUpdated(obj interface{}) {
+// Close closes the output file, if any
+func (d *Default) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	d.out = ioutil.Discard
+	return err
 }
 
-// ObjDeleted handle object deleted event
-func (d *Default) ObjDeleted(old, new interface{}) {
+// print writes one line per event, events from several
+// controllers may arrive at the same time
+func (d *Default) print(r eventRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.out == nil {
+		d.out = os.Stdout
+		d.format = "json"
+	}
+
+	if d.format == "table" && !d.header {
+		if _, err := fmt.Fprintln(d.out, tableHeader()); err != nil {
+			return err
+		}
+		d.header = true
+	}
+	_, err := fmt.Fprintln(d.out, formatters[d.format](r, d.color))
+	return err
+}
 
+// isTerminal reports whether w is a character device, colors
+// are only written to terminals
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
 }
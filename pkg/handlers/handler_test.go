@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+)
+
+func TestDefaultInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.Default
+		want    string
+		wantErr bool
+	}{
+		{"json by default", config.Default{}, "json", false},
+		{"stderr", config.Default{Format: "logfmt", Output: "stderr"}, "logfmt", false},
+		{"unknown format", config.Default{Format: "yaml"}, "", true},
+		{"unwritable output", config.Default{Output: filepath.Join(t.TempDir(), "missing", "out.log")}, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &Default{}
+			err := d.Init(&config.Config{Handler: config.Handler{Default: test.conf}})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Init() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if err == nil && d.format != test.want {
+				t.Errorf("format = %s, want %s", d.format, test.want)
+			}
+		})
+	}
+}
+
+func TestDefaultWritesToFile(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "events.log")
+	d := &Default{}
+	if err := d.Init(&config.Config{Handler: config.Handler{Default: config.Default{Format: "table", Output: output}}}); err != nil {
+		t.Fatalf("Init() = %v", err)
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if err := d.Handle(context.Background(), Event{Type: "create", Kind: "Pod", Namespace: "default", Name: name, Object: testPod(name)}); err != nil {
+			t.Fatalf("Handle() = %v", err)
+		}
+	}
+	if err := Shutdown(context.Background(), d); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	// events handed over after Close are discarded
+	if err := d.Handle(context.Background(), Event{Type: "delete", Kind: "Pod", Name: "c", Object: testPod("c")}); err != nil {
+		t.Fatalf("Handle() after Close = %v", err)
+	}
+
+	b, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "TIME") || !strings.Contains(lines[1], " a ") || !strings.Contains(lines[2], " b ") {
+		t.Errorf("output = %q, want a header and a row per event", lines)
+	}
+}
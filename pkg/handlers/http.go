@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	chatMaxAttempts       = 3
+	chatDefaultRetryAfter = time.Second
+)
+
+// postJSON posts msg to a chat incoming webhook, 429 responses are
+// retried after the delay given in the Retry-After header,
+// other client errors are Permanent
+func postJSON(ctx context.Context, client *http.Client, url string, msg interface{}, sink string) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+		if err != nil {
+			return Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < chatMaxAttempts:
+			delay := retryAfter(resp.Header.Get("Retry-After"), chatDefaultRetryAfter)
+			logrus.WithField("pkg", "k8swatch-"+sink).Warnf("Rate limited by %s, retry in %v", sink, delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests:
+			return Permanent(fmt.Errorf("%s responded %s: %s", sink, resp.Status, body))
+		case resp.StatusCode/100 != 2:
+			return fmt.Errorf("%s responded %s: %s", sink, resp.Status, body)
+		default:
+			return nil
+		}
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPostJSON(t *testing.T) {
+	tests := []struct {
+		name          string
+		statuses      []int
+		wantErr       bool
+		wantPermanent bool
+		wantRequests  int32
+	}{
+		{"ok", nil, false, false, 1},
+		{"rate limited once", []int{http.StatusTooManyRequests}, false, false, 2},
+		{"rate limited", []int{429, 429, 429}, true, false, 3},
+		{"bad request", []int{http.StatusBadRequest}, true, true, 1},
+		{"not found", []int{http.StatusNotFound}, true, true, 1},
+		{"server error", []int{http.StatusBadGateway}, true, false, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", r.Header.Get("Content-Type"))
+				}
+				n := atomic.AddInt32(&requests, 1)
+				if int(n) <= len(test.statuses) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(test.statuses[n-1])
+				}
+			}))
+			defer server.Close()
+
+			err := postJSON(context.Background(), server.Client(), server.URL, map[string]string{"text": "hi"}, "test")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("postJSON() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if IsPermanent(err) != test.wantPermanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), test.wantPermanent)
+			}
+			if got := atomic.LoadInt32(&requests); got != test.wantRequests {
+				t.Errorf("requests = %d, want %d", got, test.wantRequests)
+			}
+		})
+	}
+}
+
+func TestPostJSONCanceledWhileRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := postJSON(ctx, server.Client(), server.URL, nil, "test"); err != context.DeadlineExceeded {
+		t.Errorf("postJSON() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", time.Second},
+		{"3", 3 * time.Second},
+		{"0", 0},
+		{"-1", time.Second},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", time.Second},
+	}
+	for _, test := range tests {
+		if got := retryAfter(test.header, time.Second); got != test.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", test.header, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+)
+
+// mattermostColors maps event types and severities to attachment colors,
+// Mattermost only understands hex colors, not Slack's good/warning/danger
+var mattermostColors = map[string]string{
+	"create":         "#2EB886",
+	"update":         "#DAA038",
+	"delete":         "#A30200",
+	SeverityInfo:     "#439FE0",
+	SeverityWarning:  "#DAA038",
+	SeverityCritical: "#A30200",
+}
+
+// Mattermost handler implement
+// Post event to a Mattermost incoming webhook
+type Mattermost struct {
+	webhookURL string
+	channel    string
+	username   string
+	iconURL    string
+	templates  *messageTemplates
+	client     *http.Client
+}
+
+type mattermostField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type mattermostAttachment struct {
+	Fallback string            `json:"fallback"`
+	Color    string            `json:"color"`
+	Title    string            `json:"title"`
+	Fields   []mattermostField `json:"fields"`
+	Footer   string            `json:"footer,omitempty"`
+}
+
+type mattermostMessage struct {
+	Channel     string                 `json:"channel,omitempty"`
+	Username    string                 `json:"username,omitempty"`
+	IconURL     string                 `json:"icon_url,omitempty"`
+	Attachments []mattermostAttachment `json:"attachments"`
+}
+
+// Init initializes handler configuration
+func (m *Mattermost) Init(c *config.Config) error {
+	conf := c.Handler.Mattermost
+	if conf.WebhookURL == "" {
+		return fmt.Errorf("missing webhook url for mattermost handler")
+	}
+	templates, err := newMessageTemplates("mattermost", conf.Templates, "`")
+	if err != nil {
+		return err
+	}
+	m.webhookURL = conf.WebhookURL
+	m.channel = conf.Channel
+	m.username = conf.Username
+	m.iconURL = conf.IconURL
+	m.templates = templates
+	m.client = &http.Client{Timeout: 10 * time.Second}
+	return nil
+}
+
+// Handle posts e to the webhook
+func (m *Mattermost) Handle(ctx context.Context, e Event) error {
+	r := newEventRecord(e)
+	msg := mattermostMessage{
+		Channel:     m.channel,
+		Username:    m.username,
+		IconURL:     m.iconURL,
+		Attachments: []mattermostAttachment{newMattermostAttachment(r, m.templates.render(r, e))},
+	}
+	return postJSON(ctx, m.client, m.webhookURL, msg, "mattermost")
+}
+
+// newMattermostAttachment builds a legacy attachment, Mattermost
+// ignores Slack's Block Kit blocks
+func newMattermostAttachment(r eventRecord, title string) mattermostAttachment {
+	fields := []mattermostField{
+		{Title: "Kind", Value: r.Kind, Short: true},
+		{Title: "Event", Value: r.EventType, Short: true},
+	}
+	if r.Namespace != "" {
+		fields = append(fields, mattermostField{Title: "Namespace", Value: r.Namespace, Short: true})
+	}
+	fields = append(fields, mattermostField{Title: "Name", Value: r.Name, Short: true})
+	if r.Owner != nil {
+		fields = append(fields, mattermostField{Title: "Owner", Value: r.Owner.String(), Short: true})
+	}
+	if !r.Diff.Empty() {
+		fields = append(fields, mattermostField{Title: "Changes", Value: "```\n" + r.Diff.String() + "\n```"})
+	}
+
+	return mattermostAttachment{
+		Fallback: title,
+		Color:    mattermostColors[colorKey(r)],
+		Title:    title,
+		Fields:   fields,
+		Footer:   fmt.Sprintf("uid %s | resourceVersion %s | %s", r.UID, r.ResourceVersion, r.Timestamp),
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/diff"
+)
+
+func TestMattermostHandle(t *testing.T) {
+	var msg mattermostMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("invalid message: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := &Mattermost{}
+	conf := config.Mattermost{WebhookURL: server.URL, Channel: "ops", Username: "k8swatch", IconURL: "http://icon"}
+	if err := m.Init(&config.Config{Handler: config.Handler{Mattermost: conf}}); err != nil {
+		t.Fatalf("Init() = %v", err)
+	}
+	if err := (&Mattermost{}).Init(&config.Config{}); err == nil {
+		t.Error("Init() without a webhook url = nil, want an error")
+	}
+
+	e := Event{Type: "create", Kind: "Pod", Namespace: "default", Name: "web", Object: testPod("web")}
+	if err := m.Handle(context.Background(), e); err != nil {
+		t.Fatalf("Handle() = %v", err)
+	}
+	if msg.Channel != "ops" || msg.Username != "k8swatch" || msg.IconURL != "http://icon" {
+		t.Errorf("message = %+v, want the configured channel, username and icon", msg)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Title != "Pod `default/web` has been created" || msg.Attachments[0].Color != "#2EB886" {
+		t.Errorf("attachments = %+v, want a green attachment about the created pod", msg.Attachments)
+	}
+}
+
+func TestNewMattermostAttachment(t *testing.T) {
+	updated := eventRecord{
+		Kind:      "Deployment",
+		Namespace: "default",
+		Name:      "web",
+		EventType: "update",
+		Owner:     &Owner{Kind: "Argo", Namespace: "default", Name: "app"},
+		Diff: &diff.Diff{
+			Patch:   []diff.Operation{{Op: "replace", Path: "/spec/replicas", Value: 2}},
+			Summary: []string{"spec.replicas: 1 → 2"},
+		},
+	}
+	node := eventRecord{Kind: "Node", Name: "a", EventType: "notification", Severity: SeverityWarning}
+
+	tests := []struct {
+		name       string
+		record     eventRecord
+		wantColor  string
+		wantFields []string
+	}{
+		{
+			"update",
+			updated,
+			"#DAA038",
+			[]string{"Kind=Deployment", "Event=update", "Namespace=default", "Name=web", "Owner=Argo default/app", "Changes=```\nspec.replicas: 1 → 2\n```"},
+		},
+		{"cluster scoped notification", node, "#DAA038", []string{"Kind=Node", "Event=notification", "Name=a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newMattermostAttachment(test.record, "title")
+			var fields []string
+			for _, f := range a.Fields {
+				fields = append(fields, f.Title+"="+f.Value)
+			}
+			if a.Color != test.wantColor || !reflect.DeepEqual(fields, test.wantFields) {
+				t.Errorf("newMattermostAttachment() = %s, %q, want %s, %q", a.Color, fields, test.wantColor, test.wantFields)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+)
+
+// msTeamsColors maps event types and severities to card theme colors
+var msTeamsColors = map[string]string{
+	"create":         "2EB886",
+	"update":         "DAA038",
+	"delete":         "A30200",
+	SeverityInfo:     "439FE0",
+	SeverityWarning:  "DAA038",
+	SeverityCritical: "A30200",
+}
+
+// MSTeams handler implement
+// Post event as a MessageCard to a Microsoft Teams incoming webhook
+type MSTeams struct {
+	webhookURL string
+	templates  *messageTemplates
+	client     *http.Client
+}
+
+type msTeamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type msTeamsSection struct {
+	ActivityTitle    string        `json:"activityTitle"`
+	ActivitySubtitle string        `json:"activitySubtitle,omitempty"`
+	Facts            []msTeamsFact `json:"facts"`
+	Markdown         bool          `json:"markdown"`
+}
+
+type msTeamsCard struct {
+	Type       string           `json:"@type"`
+	Context    string           `json:"@context"`
+	ThemeColor string           `json:"themeColor"`
+	Summary    string           `json:"summary"`
+	Title      string           `json:"title"`
+	Sections   []msTeamsSection `json:"sections"`
+}
+
+// Init initializes handler configuration
+func (m *MSTeams) Init(c *config.Config) error {
+	if c.Handler.MSTeams.WebhookURL == "" {
+		return fmt.Errorf("missing webhook url for msteams handler")
+	}
+	templates, err := newMessageTemplates("msteams", c.Handler.MSTeams.Templates, "")
+	if err != nil {
+		return err
+	}
+	m.webhookURL = c.Handler.MSTeams.WebhookURL
+	m.templates = templates
+	m.client = &http.Client{Timeout: 10 * time.Second}
+	return nil
+}
+
+// Handle posts e to the webhook
+func (m *MSTeams) Handle(ctx context.Context, e Event) error {
+	r := newEventRecord(e)
+	title := m.templates.render(r, e)
+	return postJSON(ctx, m.client, m.webhookURL, newMSTeamsCard(r, title), "msteams")
+}
+
+func newMSTeamsCard(r eventRecord, title string) msTeamsCard {
+	facts := []msTeamsFact{
+		{Name: "Kind", Value: r.Kind},
+		{Name: "Event", Value: r.EventType},
+	}
+	if r.Namespace != "" {
+		facts = append(facts, msTeamsFact{Name: "Namespace", Value: r.Namespace})
+	}
+	facts = append(facts,
+		msTeamsFact{Name: "Name", Value: r.Name},
+	)
+	if r.Owner != nil {
+		facts = append(facts, msTeamsFact{Name: "Owner", Value: r.Owner.String()})
+	}
+	facts = append(facts,
+		msTeamsFact{Name: "UID", Value: r.UID},
+		msTeamsFact{Name: "ResourceVersion", Value: r.ResourceVersion},
+	)
+	if !r.Diff.Empty() {
+		facts = append(facts, msTeamsFact{Name: "Changes", Value: strings.Join(r.Diff.Summary, "<br>")})
+	}
+
+	return msTeamsCard{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		ThemeColor: msTeamsColors[colorKey(r)],
+		Summary:    title,
+		Title:      title,
+		Sections: []msTeamsSection{
+			{
+				ActivityTitle:    "k8swatch",
+				ActivitySubtitle: r.Timestamp,
+				Facts:            facts,
+				Markdown:         true,
+			},
+		},
+	}
+}
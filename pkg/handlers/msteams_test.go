@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/diff"
+)
+
+func TestMSTeamsInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.MSTeams
+		wantErr bool
+	}{
+		{"webhook url", config.MSTeams{WebhookURL: "http://teams"}, false},
+		{"missing webhook url", config.MSTeams{}, true},
+		{"invalid template", config.MSTeams{WebhookURL: "http://teams", Templates: map[string]string{"create": "{{ .Nmae }}"}}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := (&MSTeams{}).Init(&config.Config{Handler: config.Handler{MSTeams: test.conf}})
+			if (err != nil) != test.wantErr {
+				t.Errorf("Init() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestMSTeamsHandle(t *testing.T) {
+	var card msTeamsCard
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&card); err != nil {
+			t.Errorf("invalid card: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := &MSTeams{}
+	if err := m.Init(&config.Config{Handler: config.Handler{MSTeams: config.MSTeams{WebhookURL: server.URL}}}); err != nil {
+		t.Fatalf("Init() = %v", err)
+	}
+	e := Event{Type: "delete", Kind: "Pod", Namespace: "default", Name: "web", Object: testPod("web")}
+	if err := m.Handle(context.Background(), e); err != nil {
+		t.Fatalf("Handle() = %v", err)
+	}
+	if card.Type != "MessageCard" || card.Title != "Pod default/web has been deleted" || card.ThemeColor != "A30200" {
+		t.Errorf("card = %+v, want a red MessageCard about the deleted pod", card)
+	}
+}
+
+func TestNewMSTeamsCard(t *testing.T) {
+	updated := eventRecord{
+		Kind:            "Deployment",
+		Namespace:       "default",
+		Name:            "web",
+		EventType:       "update",
+		UID:             "u1",
+		ResourceVersion: "7",
+		Owner:           &Owner{Kind: "Argo", Namespace: "default", Name: "app"},
+		Diff: &diff.Diff{
+			Patch:   []diff.Operation{{Op: "replace", Path: "/spec/replicas", Value: 2}, {Op: "replace", Path: "/spec/paused", Value: true}},
+			Summary: []string{"spec.replicas: 1 → 2", "spec.paused: false → true"},
+		},
+	}
+	node := eventRecord{Kind: "Node", Name: "a", EventType: "notification", Severity: SeverityCritical, UID: "u2", ResourceVersion: "8"}
+
+	tests := []struct {
+		name      string
+		record    eventRecord
+		wantColor string
+		wantFacts []string
+	}{
+		{
+			"update",
+			updated,
+			"DAA038",
+			[]string{"Kind=Deployment", "Event=update", "Namespace=default", "Name=web", "Owner=Argo default/app", "UID=u1", "ResourceVersion=7", "Changes=spec.replicas: 1 → 2<br>spec.paused: false → true"},
+		},
+		{"cluster scoped notification", node, "A30200", []string{"Kind=Node", "Event=notification", "Name=a", "UID=u2", "ResourceVersion=8"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			card := newMSTeamsCard(test.record, "title")
+			var facts []string
+			for _, f := range card.Sections[0].Facts {
+				facts = append(facts, f.Name+"="+f.Value)
+			}
+			if card.ThemeColor != test.wantColor || !reflect.DeepEqual(facts, test.wantFacts) {
+				t.Errorf("newMSTeamsCard() = %s, %q, want %s, %q", card.ThemeColor, facts, test.wantColor, test.wantFacts)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package handlers
+
+// Severities of a Notification
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Notification is a high level event derived from watched objects,
+// e.g. a container killed for running out of memory
+type Notification struct {
+	// Reason is a short machine readable cause, e.g. OOMKilled
+	Reason string
+	// Message is ready to be shown, e.g. container web OOMKilled (exit 137)
+	Message  string
+	Severity string
+	// Object the notification is about
+	Object interface{}
+	// Owner is the top level controller of Object, if resolved
+	Owner *Owner
+}
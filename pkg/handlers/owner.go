@@ -0,0 +1,20 @@
+package handlers
+
+// Owner is the top level controller of an object, e.g. the Deployment
+// of a pod, resolved through the ReplicaSet or Job in between
+type Owner struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// String returns the owner as Kind namespace/name, empty for no owner
+func (o *Owner) String() string {
+	if o == nil {
+		return ""
+	}
+	if o.Namespace == "" {
+		return o.Kind + " " + o.Name
+	}
+	return o.Kind + " " + o.Namespace + "/" + o.Name
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+)
+
+// slackColors maps event types and severities to attachment colors
+var slackColors = map[string]string{
+	"create":         "good",
+	"update":         "warning",
+	"delete":         "danger",
+	SeverityInfo:     "#439FE0",
+	SeverityWarning:  "warning",
+	SeverityCritical: "danger",
+}
+
+// slackMaxText is the longest text of a section block Slack accepts
+const slackMaxText = 3000
+
+// slackMaxLine cuts the long changes of the diff block
+const slackMaxLine = 500
+
+// Slack handler implement
+// Post event to a Slack incoming webhook
+type Slack struct {
+	webhookURL string
+	channel    string
+	templates  *messageTemplates
+	client     *http.Client
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackAttachment struct {
+	Color    string       `json:"color"`
+	Fallback string       `json:"fallback"`
+	Blocks   []slackBlock `json:"blocks"`
+}
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+// Init initializes handler configuration
+func (s *Slack) Init(c *config.Config) error {
+	if c.Handler.Slack.WebhookURL == "" {
+		return fmt.Errorf("missing webhook url for slack handler")
+	}
+	templates, err := newMessageTemplates("slack", c.Handler.Slack.Templates, "`")
+	if err != nil {
+		return err
+	}
+	s.webhookURL = c.Handler.Slack.WebhookURL
+	s.channel = c.Handler.Slack.Channel
+	s.templates = templates
+	s.client = &http.Client{Timeout: 10 * time.Second}
+	return nil
+}
+
+// Handle posts e to the webhook
+func (s *Slack) Handle(ctx context.Context, e Event) error {
+	r := newEventRecord(e)
+	title := s.templates.render(r, e)
+	return postJSON(ctx, s.client, s.webhookURL, newSlackMessage(r, title, s.channel), "slack")
+}
+
+func newSlackMessage(r eventRecord, title, channel string) slackMessage {
+	fields := []slackText{
+		{Type: "mrkdwn", Text: "*Kind*\n" + r.Kind},
+		{Type: "mrkdwn", Text: "*Event*\n" + r.EventType},
+	}
+	if r.Namespace != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Namespace*\n" + r.Namespace})
+	}
+	fields = append(fields, slackText{Type: "mrkdwn", Text: "*Name*\n" + r.Name})
+	if r.Owner != nil {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Owner*\n" + r.Owner.String()})
+	}
+
+	blocks := []slackBlock{
+		{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncate(title, slackMaxText)}},
+		{Type: "section", Fields: fields},
+	}
+	if !r.Diff.Empty() {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: slackDiff(r.Diff.Summary)}})
+	}
+	blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{
+		{Type: "mrkdwn", Text: fmt.Sprintf("uid %s | resourceVersion %s | %s", r.UID, r.ResourceVersion, r.Timestamp)},
+	}})
+
+	return slackMessage{
+		Channel: channel,
+		Attachments: []slackAttachment{
+			{
+				Color:    slackColors[colorKey(r)],
+				Fallback: title,
+				Blocks:   blocks,
+			},
+		},
+	}
+}
+
+// slackDiff formats the diff summary as a code block within the section limit,
+// the changes left out are counted instead
+func slackDiff(summary []string) string {
+	const fence = "```"
+	// room for the fences and the count of the changes left out
+	limit := slackMaxText - 2*len(fence) - len("\n... 1000000 more changes")
+	var lines []string
+	size := 0
+	for i, line := range summary {
+		line = truncate(line, slackMaxLine)
+		if size+len(line)+1 > limit {
+			lines = append(lines, fmt.Sprintf("... %d more changes", len(summary)-i))
+			break
+		}
+		lines = append(lines, line)
+		size += len(line) + 1
+	}
+	return fence + strings.Join(lines, "\n") + fence
+}
+
+// truncate cuts s to at most n bytes without splitting a rune
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n - len("...")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+)
+
+// slackServer stands in for a Slack incoming webhook, it responds with the
+// statuses in turn then 200, and decodes every message it receives
+func slackServer(t *testing.T, statuses ...int) (*httptest.Server, *[]slackMessage, *int32) {
+	t.Helper()
+	var messages []slackMessage
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("invalid message: %v", err)
+		}
+		messages = append(messages, msg)
+		if int(n) <= len(statuses) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &messages, &requests
+}
+
+func newTestSlack(t *testing.T, url string) *Slack {
+	t.Helper()
+	s := &Slack{}
+	c := &config.Config{Handler: config.Handler{Slack: config.Slack{WebhookURL: url, Channel: "#k8s"}}}
+	if err := s.Init(c); err != nil {
+		t.Fatalf("Init() = %v", err)
+	}
+	return s
+}
+
+func TestSlackInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.Slack
+		wantErr bool
+	}{
+		{"webhook url", config.Slack{WebhookURL: "http://slack"}, false},
+		{"missing webhook url", config.Slack{}, true},
+		{"template", config.Slack{WebhookURL: "http://slack", Templates: map[string]string{"create": "{{ .Name }}"}}, false},
+		{"unknown event type", config.Slack{WebhookURL: "http://slack", Templates: map[string]string{"created": "{{ .Name }}"}}, true},
+	}
+	for _, test := range tests {
+		err := (&Slack{}).Init(&config.Config{Handler: config.Handler{Slack: test.conf}})
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: Init() = %v, wantErr %v", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestSlackHandle(t *testing.T) {
+	tests := []struct {
+		name          string
+		statuses      []int
+		wantErr       bool
+		wantPermanent bool
+		wantRequests  int32
+	}{
+		{"delivered", nil, false, false, 1},
+		{"rate limited then delivered", []int{http.StatusTooManyRequests}, false, false, 2},
+		{"rate limited on every attempt", []int{429, 429, 429}, true, false, chatMaxAttempts},
+		{"rejected", []int{http.StatusBadRequest}, true, true, 1},
+		{"unavailable", []int{http.StatusServiceUnavailable}, true, false, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, _, requests := slackServer(t, test.statuses...)
+			err := newTestSlack(t, server.URL).Handle(context.Background(), Event{Type: "create", Kind: "Pod", Namespace: "default", Name: "web", Object: testPod("web")})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Handle() = %v, wantErr %v", err, test.wantErr)
+			}
+			if IsPermanent(err) != test.wantPermanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), test.wantPermanent)
+			}
+			if got := atomic.LoadInt32(requests); got != test.wantRequests {
+				t.Errorf("requests = %d, want %d", got, test.wantRequests)
+			}
+		})
+	}
+}
+
+func TestSlackMessage(t *testing.T) {
+	server, messages, _ := slackServer(t)
+	e := Event{
+		Type:      "update",
+		Kind:      "Pod",
+		Namespace: "default",
+		Name:      "web",
+		Object:    testPod("web"),
+		OldObject: testPod("web"),
+		Owner:     &Owner{Kind: "Deployment", Namespace: "default", Name: "web"},
+		Cluster:   "prod",
+	}
+	if err := newTestSlack(t, server.URL).Handle(context.Background(), e); err != nil {
+		t.Fatalf("Handle() = %v", err)
+	}
+	if len(*messages) != 1 {
+		t.Fatalf("received %d messages, want 1", len(*messages))
+	}
+
+	msg := (*messages)[0]
+	if msg.Channel != "#k8s" {
+		t.Errorf("channel = %q, want #k8s", msg.Channel)
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("received %d attachments, want 1", len(msg.Attachments))
+	}
+	a := msg.Attachments[0]
+	if a.Color != slackColors["update"] {
+		t.Errorf("color = %q, want %q", a.Color, slackColors["update"])
+	}
+	want := "[prod] Pod `default/web` of Deployment `default/web` has been updated"
+	if a.Fallback != want || a.Blocks[0].Text.Text != want {
+		t.Errorf("title = %q, want %q", a.Blocks[0].Text.Text, want)
+	}
+	var fields []string
+	for _, f := range a.Blocks[1].Fields {
+		fields = append(fields, f.Text)
+	}
+	if got := strings.Join(fields, "|"); got != "*Kind*\nPod|*Event*\nupdate|*Namespace*\ndefault|*Name*\nweb|*Owner*\nDeployment default/web" {
+		t.Errorf("fields = %q", got)
+	}
+}
+
+func TestSlackDiff(t *testing.T) {
+	long := strings.Repeat("x", 2*slackMaxLine)
+	many := make([]string, 200)
+	for i := range many {
+		many[i] = fmt.Sprintf("metadata.labels.label-%03d: added %q", i, strings.Repeat("v", 20))
+	}
+
+	tests := []struct {
+		name     string
+		summary  []string
+		want     string
+		wantMore string
+	}{
+		{"short", []string{"spec.replicas: 1 → 3"}, "```spec.replicas: 1 → 3```", ""},
+		{"long change", []string{long}, "```" + long[:slackMaxLine-3] + "...```", ""},
+		{"many changes", many, "", "more changes"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := slackDiff(test.summary)
+			if len(got) > slackMaxText {
+				t.Errorf("slackDiff() is %d characters long, want at most %d", len(got), slackMaxText)
+			}
+			if test.want != "" && got != test.want {
+				t.Errorf("slackDiff() = %q, want %q", got, test.want)
+			}
+			if !strings.HasSuffix(got, test.wantMore+"```") {
+				t.Errorf("slackDiff() = %q, want it to end with %q", got, test.wantMore)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly 10", 10, "exactly 10"},
+		{"truncated text", 10, "truncat..."},
+		{"ééééé", 8, "éé..."},
+	}
+	for _, test := range tests {
+		if got := truncate(test.s, test.n); got != test.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", test.s, test.n, got, test.want)
+		}
+	}
+}
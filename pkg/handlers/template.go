@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/walk1ng/k8swatch/pkg/diff"
+	"github.com/walk1ng/k8swatch/pkg/utils"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// templateEventTypes are the event types a template can be configured for
+var templateEventTypes = []string{"create", "update", "delete", "notification"}
+
+// messageTemplates renders the text of the events posted by a handler,
+// a template configured for an event type replaces the built-in one
+type messageTemplates struct {
+	byType   map[string]*template.Template
+	defaults map[string]*template.Template
+}
+
+// templateData is what a template is executed with, e.g.
+// {{ .Kind }} {{ .Name }} has {{ .Object.spec.replicas }} replicas
+type templateData struct {
+	eventRecord
+	Cluster string
+	// Object and OldObject are unstructured, OldObject is only set for updates,
+	// the values of Secrets are redacted
+	Object    map[string]interface{}
+	OldObject map[string]interface{}
+}
+
+// defaultTemplates returns the built-in templates, quote
+// surrounds names, e.g. a backtick for markdown handlers
+func defaultTemplates(quote string) map[string]string {
+	// name reads the object or, within with .Owner, its owner
+	name := quote + "{{ with .Namespace }}{{ . }}/{{ end }}{{ .Name }}" + quote
+	subject := "{{ with .Cluster }}[{{ . }}] {{ end }}{{ .Kind }} " + name + "{{ with .Owner }} of {{ .Kind }} " + name + "{{ end }}"
+	return map[string]string{
+		"create":       subject + " has been created",
+		"update":       subject + " has been updated",
+		"delete":       subject + " has been deleted",
+		"notification": subject + ": {{ .Message }}",
+	}
+}
+
+// newMessageTemplates parses the templates configured for a handler by event type,
+// an unknown event type or a template failing to parse or to render sample data is an error
+func newMessageTemplates(handler string, configured map[string]string, quote string) (*messageTemplates, error) {
+	t := &messageTemplates{
+		byType:   map[string]*template.Template{},
+		defaults: map[string]*template.Template{},
+	}
+
+	for eventType, text := range defaultTemplates(quote) {
+		t.defaults[eventType] = template.Must(newTemplate(handler+"-"+eventType, text))
+	}
+
+	for eventType, text := range configured {
+		if !utils.ContainsFold(templateEventTypes, eventType) {
+			return nil, fmt.Errorf("unknown event type %q in templates of %s handler, expected one of %s",
+				eventType, handler, strings.Join(templateEventTypes, ", "))
+		}
+		tmpl, err := newTemplate(handler+"-"+eventType, text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template of %s handler: %v", eventType, handler, err)
+		}
+		if err := dryRun(tmpl, strings.ToLower(eventType)); err != nil {
+			return nil, fmt.Errorf("invalid %s template of %s handler: %v", eventType, handler, err)
+		}
+		t.byType[strings.ToLower(eventType)] = tmpl
+	}
+	return t, nil
+}
+
+func newTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+// dryRunErrors are the errors of a dry run which do not depend on the data, e.g. {{ .Nmae }},
+// the fields of Object and OldObject are only known at runtime, so the values they lead to are not checked
+var dryRunErrors = []string{"can't evaluate field", "wrong number of args"}
+
+// dryRun executes tmpl with sample data of eventType to catch the errors of every event at startup
+func dryRun(tmpl *template.Template, eventType string) error {
+	data := templateData{
+		eventRecord: eventRecord{
+			Kind:            "Deployment",
+			Namespace:       "default",
+			Name:            "sample",
+			EventType:       eventType,
+			Timestamp:       time.Now().UTC().Format(time.RFC3339),
+			UID:             "00000000-0000-0000-0000-000000000000",
+			ResourceVersion: "1",
+			Diff:            &diff.Diff{Summary: []string{"spec.replicas: 1 → 2"}},
+			Severity:        SeverityInfo,
+			Reason:          "Sample",
+			Message:         "sample message",
+			Owner:           &Owner{Kind: "Deployment", Namespace: "default", Name: "sample"},
+		},
+		Cluster:   "sample",
+		Object:    map[string]interface{}{},
+		OldObject: map[string]interface{}{},
+	}
+	_, err := execute(tmpl, data)
+	if err == nil {
+		return nil
+	}
+	for _, e := range dryRunErrors {
+		if strings.Contains(err.Error(), e) {
+			return err
+		}
+	}
+	return nil
+}
+
+// render returns the text of an event, a configured template failing
+// to execute is logged and the built-in one is used instead
+func (t *messageTemplates) render(r eventRecord, e Event) string {
+	data := templateData{
+		eventRecord: r,
+		Cluster:     e.Cluster,
+		Object:      toUnstructured(diff.Redact(e.Object)),
+		OldObject:   toUnstructured(diff.Redact(e.OldObject)),
+	}
+
+	if tmpl, ok := t.byType[r.EventType]; ok {
+		text, err := execute(tmpl, data)
+		if err == nil {
+			return text
+		}
+		logrus.WithField("pkg", "k8swatch-template").Errorf("Failed to render %s: %v", tmpl.Name(), err)
+	}
+
+	text, err := execute(t.defaults[r.EventType], data)
+	if err != nil {
+		// the built-in templates only read fields of the record
+		return fmt.Sprintf("%s %s %s", r.Kind, r.Name, r.EventType)
+	}
+	return text
+}
+
+func execute(tmpl *template.Template, data templateData) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// toUnstructured returns the fields of obj by their json names, nil if obj cannot be converted
+func toUnstructured(obj interface{}) map[string]interface{} {
+	switch object := obj.(type) {
+	case nil:
+		return nil
+	case runtime.Unstructured:
+		return object.UnstructuredContent()
+	}
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil
+	}
+	return m
+}
+
+// templateFuncs are helpers in the spirit of Sprig, the last argument is
+// the piped value, e.g. {{ .Name | trunc 20 | upper }}
+var templateFuncs = template.FuncMap{
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"title":      strings.Title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+	"join":       join,
+	"trunc":      trunc,
+	"indent":     indent,
+	"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+	"quote":      func(v interface{}) string { return fmt.Sprintf("%q", fmt.Sprint(v)) },
+	"default":    defaultValue,
+	"empty":      empty,
+	"toJson":     toJSON,
+	"now":        time.Now,
+	"date":       func(layout string, t time.Time) string { return t.Format(layout) },
+	"dict":       dict,
+}
+
+func join(sep string, v interface{}) string {
+	switch list := v.(type) {
+	case []string:
+		return strings.Join(list, sep)
+	case []interface{}:
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, sep)
+	}
+	return fmt.Sprint(v)
+}
+
+// trunc shortens s to n characters
+func trunc(n int, s string) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+// defaultValue returns v, or def when v is empty
+func defaultValue(def, v interface{}) interface{} {
+	if empty(v) {
+		return def
+	}
+	return v
+}
+
+func empty(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case bool:
+		return !value
+	case int, int32, int64, float64:
+		return fmt.Sprint(value) == "0"
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+	return false
+}
+
+func toJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// dict builds a map from key value pairs, e.g. to pass several values to a template
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict expects key value pairs")
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		m[fmt.Sprint(pairs[i])] = pairs[i+1]
+	}
+	return m, nil
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewMessageTemplates(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured map[string]string
+		wantErr    bool
+	}{
+		{"none", nil, false},
+		{"every event type", map[string]string{"create": "{{ .Name }}", "Update": "{{ .Diff }}", "delete": "{{ .Kind }}", "notification": "{{ .Message }}"}, false},
+		{"object fields", map[string]string{"update": "{{ .Object.spec.replicas }} {{ .Object.metadata.name | upper }}"}, false},
+		{"unknown event type", map[string]string{"scale": "{{ .Name }}"}, true},
+		{"parse error", map[string]string{"create": "{{ .Name "}, true},
+		{"unknown function", map[string]string{"create": "{{ .Name | shout }}"}, true},
+		{"unknown field", map[string]string{"create": "{{ .Nmae }}"}, true},
+		{"wrong number of args", map[string]string{"create": "{{ trunc .Name }}"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newMessageTemplates("test", test.configured, "")
+			if (err != nil) != test.wantErr {
+				t.Errorf("newMessageTemplates() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	replicas := int32(3)
+	web := &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec:       apps_v1.DeploymentSpec{Replicas: &replicas},
+	}
+	created := Event{Type: "create", Kind: "Deployment", Namespace: "default", Name: "web", Object: web}
+	owned := created
+	owned.Owner = &Owner{Kind: "Argo", Namespace: "default", Name: "app"}
+	notified := created
+	notified.Type = "notification"
+	notified.Cluster = "prod"
+	notified.Notification = &Notification{Reason: "RolloutStalled", Message: "rollout stalled", Severity: SeverityCritical}
+
+	tests := []struct {
+		name       string
+		configured map[string]string
+		quote      string
+		event      Event
+		want       string
+	}{
+		{"default", nil, "", created, "Deployment default/web has been created"},
+		{"quoted", nil, "`", created, "Deployment `default/web` has been created"},
+		{"owner", nil, "", owned, "Deployment default/web of Argo default/app has been created"},
+		{"notification", nil, "", notified, "[prod] Deployment default/web: rollout stalled"},
+		{"configured", map[string]string{"create": "{{ .Name }} wants {{ .Object.spec.replicas }} replicas"}, "", created, "web wants 3 replicas"},
+		{"configured for another type", map[string]string{"delete": "gone"}, "", created, "Deployment default/web has been created"},
+		{"secret", map[string]string{"update": "{{ .Object.data.password }}"}, "", Event{Type: "update", Kind: "Secret", Object: &api_v1.Secret{Data: map[string][]byte{"password": []byte("hunter2")}}}, "<redacted>"},
+		{"failing at runtime", map[string]string{"create": "{{ .Object.spec.replicas | upper }}"}, "", created, "Deployment default/web has been created"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			templates, err := newMessageTemplates("test", test.configured, test.quote)
+			if err != nil {
+				t.Fatalf("newMessageTemplates() = %v", err)
+			}
+			if got := templates.render(newEventRecord(test.event), test.event); got != test.want {
+				t.Errorf("render() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`{{ "web" | upper }}`, "WEB"},
+		{`{{ "web-server" | trunc 3 }}`, "web"},
+		{`{{ "ünïcode" | trunc 3 }}`, "ünï"},
+		{`{{ "web-1" | trimPrefix "web-" }}`, "1"},
+		{`{{ "a.b" | replace "." "/" }}`, "a/b"},
+		{`{{ "a,b" | split "," | join " " }}`, "a b"},
+		{`{{ "a\nb" | indent 2 }}`, "a\n  b"},
+		{`{{ "" | default "none" }}`, "none"},
+		{`{{ "x" | default "none" }}`, "x"},
+		{`{{ 0 | empty }}`, "true"},
+		{`{{ "web" | quote }}`, `"web"`},
+		{`{{ dict "a" 1 | toJson }}`, `{"a":1}`},
+		{`{{ if "web-1" | hasPrefix "web" }}yes{{ end }}`, "yes"},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			tmpl, err := newTemplate("test", test.text)
+			if err != nil {
+				t.Fatalf("newTemplate() = %v", err)
+			}
+			got, err := execute(tmpl, templateData{})
+			if err != nil {
+				t.Fatalf("execute() = %v", err)
+			}
+			if got != test.want {
+				t.Errorf("execute() = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := dict("a"); err == nil {
+		t.Error("dict() of an odd number of arguments = nil, want an error")
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+	"github.com/walk1ng/k8swatch/pkg/diff"
+)
+
+const (
+	webhookEnvelopeVersion = "k8swatch/v1"
+	webhookSignatureHeader = "X-K8swatch-Signature"
+)
+
+// Webhook handler implement
+// Post event as a JSON envelope to an arbitrary url
+type Webhook struct {
+	url       string
+	headers   map[string]string
+	token     string
+	user      string
+	pass      string
+	secret    []byte
+	templates *messageTemplates
+	client    *http.Client
+}
+
+// webhookEnvelope is the stable payload posted for every event
+type webhookEnvelope struct {
+	Version string `json:"version"`
+	eventRecord
+	// Text is rendered by the templates of the handler
+	Text string `json:"text"`
+	// Object and OldObject have the values of Secrets redacted
+	Object    interface{} `json:"object"`
+	OldObject interface{} `json:"oldObject,omitempty"`
+}
+
+// Init initializes handler configuration
+func (w *Webhook) Init(c *config.Config) error {
+	conf := c.Handler.Webhook
+	if conf.URL == "" {
+		return fmt.Errorf("missing url for webhook handler")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: conf.InsecureSkipVerify}
+	if conf.CAFile != "" {
+		ca, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read webhook ca file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("no certificates found in webhook ca file %s", conf.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	templates, err := newMessageTemplates("webhook", conf.Templates, "")
+	if err != nil {
+		return err
+	}
+
+	w.url = conf.URL
+	w.headers = conf.Headers
+	w.token = conf.BearerToken
+	w.user = conf.Username
+	w.pass = conf.Password
+	w.secret = []byte(conf.Secret)
+	w.templates = templates
+	w.client = &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig},
+	}
+	return nil
+}
+
+// Handle posts e as an envelope
+func (w *Webhook) Handle(ctx context.Context, e Event) error {
+	r := newEventRecord(e)
+	b, err := json.Marshal(webhookEnvelope{
+		Version:     webhookEnvelopeVersion,
+		eventRecord: r,
+		Text:        w.templates.render(r, e),
+		Object:      diff.Redact(e.Object),
+		OldObject:   diff.Redact(e.OldObject),
+	})
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to encode envelope: %v", err))
+	}
+	return w.post(ctx, b)
+}
+
+// post sends the payload once, a failure is Permanent unless the response is 429 or 5xx
+func (w *Webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	switch {
+	case w.token != "":
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	case w.user != "":
+		req.SetBasicAuth(w.user, w.pass)
+	}
+	if len(w.secret) != 0 {
+		req.Header.Set(webhookSignatureHeader, "sha256="+sign(w.secret, body))
+	}
+
+	resp, err := w.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("webhook responded %s: %s", resp.Status, msg)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5 {
+		return err
+	}
+	return Permanent(err)
+}
+
+// sign returns the hex encoded HMAC-SHA256 of body
+func sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/walk1ng/k8swatch/pkg/config"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWebhookHandle(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{"delivered", http.StatusOK, false, false},
+		{"accepted", http.StatusAccepted, false, false},
+		{"rejected", http.StatusBadRequest, true, true},
+		{"unauthorized", http.StatusUnauthorized, true, true},
+		{"rate limited", http.StatusTooManyRequests, true, false},
+		{"unavailable", http.StatusServiceUnavailable, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			w := &Webhook{}
+			if err := w.Init(&config.Config{Handler: config.Handler{Webhook: config.Webhook{URL: server.URL}}}); err != nil {
+				t.Fatalf("Init() = %v", err)
+			}
+			err := w.Handle(context.Background(), Event{Type: "create", Kind: "Pod", Name: "web", Object: testPod("web")})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Handle() = %v, wantErr %v", err, test.wantErr)
+			}
+			if IsPermanent(err) != test.wantPermanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), test.wantPermanent)
+			}
+		})
+	}
+}
+
+func TestWebhookRequest(t *testing.T) {
+	var (
+		body   []byte
+		header http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer server.Close()
+
+	w := &Webhook{}
+	conf := config.Webhook{
+		URL:         server.URL,
+		Headers:     map[string]string{"X-Team": "platform"},
+		BearerToken: "token",
+		Secret:      "secret",
+	}
+	if err := w.Init(&config.Config{Handler: config.Handler{Webhook: conf}}); err != nil {
+		t.Fatalf("Init() = %v", err)
+	}
+	e := Event{Type: "delete", Kind: "Pod", Namespace: "default", Name: "web", Object: testPod("web")}
+	if err := w.Handle(context.Background(), e); err != nil {
+		t.Fatalf("Handle() = %v", err)
+	}
+
+	if got, want := header.Get(webhookSignatureHeader), "sha256="+sign([]byte("secret"), body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q, want Bearer token", got)
+	}
+	if got := header.Get("X-Team"); got != "platform" {
+		t.Errorf("X-Team = %q, want platform", got)
+	}
+
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("invalid envelope: %v", err)
+	}
+	for field, want := range map[string]string{
+		"version":   webhookEnvelopeVersion,
+		"kind":      "Pod",
+		"namespace": "default",
+		"name":      "web",
+		"eventType": "delete",
+		"text":      "Pod default/web has been deleted",
+	} {
+		if got := envelope[field]; got != want {
+			t.Errorf("%s = %v, want %q", field, got, want)
+		}
+	}
+	if _, ok := envelope["oldObject"]; ok {
+		t.Errorf("oldObject is set on a delete")
+	}
+}
+
+func TestWebhookRedactsSecrets(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	w := &Webhook{}
+	if err := w.Init(&config.Config{Handler: config.Handler{Webhook: config.Webhook{URL: server.URL}}}); err != nil {
+		t.Fatalf("Init() = %v", err)
+	}
+	old := &api_v1.Secret{ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "credentials"}, Data: map[string][]byte{"password": []byte("hunter1")}}
+	updated := old.DeepCopy()
+	updated.Data["password"] = []byte("hunter2")
+	e := Event{Type: "update", Kind: "Secret", Namespace: "default", Name: "credentials", Object: updated, OldObject: old}
+	if err := w.Handle(context.Background(), e); err != nil {
+		t.Fatalf("Handle() = %v", err)
+	}
+
+	for _, value := range []string{"hunter1", "hunter2", "aHVudGVyMQ==", "aHVudGVyMg=="} {
+		if strings.Contains(string(body), value) {
+			t.Errorf("envelope %s holds the Secret value %s", body, value)
+		}
+	}
+	var envelope struct {
+		Object struct {
+			Data map[string]string `json:"data"`
+		} `json:"object"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("invalid envelope: %v", err)
+	}
+	if got := envelope.Object.Data["password"]; got != "<redacted>" {
+		t.Errorf("password = %q, want <redacted>", got)
+	}
+}
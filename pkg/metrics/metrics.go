@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+var (
+	// EventsReceived counts the events taken from the informers, and the notifications derived from them
+	EventsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8swatch_events_received_total",
+		Help: "Events received by kind and event type.",
+	}, []string{"kind", "type"})
+
+	// EventsDispatched counts the events handed to the handlers
+	EventsDispatched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8swatch_events_dispatched_total",
+		Help: "Events dispatched to the handlers by kind and event type.",
+	}, []string{"kind", "type"})
+
+	// QueueDepth is the number of events waiting in the queue of a controller
+	QueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8swatch_queue_depth",
+		Help: "Events waiting in the queue of a controller.",
+	}, []string{"controller"})
+
+	// QueueRetries counts the events requeued after an error
+	QueueRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8swatch_queue_retries_total",
+		Help: "Events requeued after a processing error by controller.",
+	}, []string{"controller"})
+
+	// QueueGiveUps counts the events dropped after too many retries
+	QueueGiveUps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8swatch_queue_give_ups_total",
+		Help: "Events dropped after too many retries by controller.",
+	}, []string{"controller"})
+
+	// HandlerDuration is the time a handler takes to deliver an event
+	HandlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k8swatch_handler_duration_seconds",
+		Help:    "Time taken by a handler to deliver an event.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"sink"})
+
+	// HandlerErrors counts the events a handler failed to deliver
+	HandlerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8swatch_handler_errors_total",
+		Help: "Events a handler failed to deliver.",
+	}, []string{"sink"})
+)
+
+// Registry holds the k8swatch metrics along with the go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		EventsReceived,
+		EventsDispatched,
+		QueueDepth,
+		QueueRetries,
+		QueueGiveUps,
+		HandlerDuration,
+		HandlerErrors,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/walk1ng/k8swatch/pkg/metrics"
+)
+
+// get returns the status and body of url, retrying until the server listens
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			defer resp.Body.Close()
+			b, _ := ioutil.ReadAll(resp.Body)
+			return resp.StatusCode, string(b)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	var ready int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Serve(ctx, addr, func() bool { return atomic.LoadInt32(&ready) == 1 })
+		close(done)
+	}()
+
+	metrics.EventsReceived.WithLabelValues("Pod", "create").Inc()
+
+	tests := []struct {
+		name       string
+		path       string
+		ready      bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"healthy", "/healthz", false, http.StatusOK, "ok"},
+		{"not ready", "/readyz", false, http.StatusServiceUnavailable, "informer caches not synced"},
+		{"ready", "/readyz", true, http.StatusOK, "ok"},
+		{"metrics", "/metrics", true, http.StatusOK, `k8swatch_events_received_total{kind="Pod",type="create"}`},
+		{"runtime metrics", "/metrics", true, http.StatusOK, "go_goroutines"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.ready {
+				atomic.StoreInt32(&ready, 1)
+			}
+			status, body := get(t, "http://"+addr+test.path)
+			if status != test.wantStatus || !strings.Contains(body, test.wantBody) {
+				t.Errorf("GET %s = %d %q, want %d with %q", test.path, status, body, test.wantStatus, test.wantBody)
+			}
+		})
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Serve() did not return once its context was done")
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchesAny reports whether s matches any of the glob patterns
+func MatchesAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsFold reports whether values contains s, ignoring case
+func ContainsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
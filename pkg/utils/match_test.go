@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestMatchesAny(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		s        string
+		want     bool
+	}{
+		{nil, "default", false},
+		{[]string{"default"}, "default", true},
+		{[]string{"kube-*"}, "kube-system", true},
+		{[]string{"kube-*"}, "default", false},
+		{[]string{"Deployment/web-?"}, "Deployment/web-1", true},
+		{[]string{"["}, "[", false},
+	}
+	for _, test := range tests {
+		if got := MatchesAny(test.patterns, test.s); got != test.want {
+			t.Errorf("MatchesAny(%q, %q) = %v, want %v", test.patterns, test.s, got, test.want)
+		}
+	}
+}
+
+func TestContainsFold(t *testing.T) {
+	tests := []struct {
+		values []string
+		s      string
+		want   bool
+	}{
+		{nil, "Pod", false},
+		{[]string{"pod"}, "Pod", true},
+		{[]string{"Deployment", "POD"}, "pod", true},
+		{[]string{"Deployment"}, "Pod", false},
+	}
+	for _, test := range tests {
+		if got := ContainsFold(test.values, test.s); got != test.want {
+			t.Errorf("ContainsFold(%q, %q) = %v, want %v", test.values, test.s, got, test.want)
+		}
+	}
+}
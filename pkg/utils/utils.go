@@ -2,18 +2,18 @@ package utils
 
 import (
 	"os"
+	"reflect"
 	"runtime"
 
-	apps_v1 "k8s.io/api/apps/v1"
-	batch_v1 "k8s.io/api/batch/v1"
-	api_v1 "k8s.io/api/core/v1"
-	ext_v1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/Sirupsen/logrus"
 
 	"k8s.io/client-go/tools/clientcmd"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -31,6 +31,19 @@ func GetClient() kubernetes.Interface {
 	return clientset
 }
 
+// GetDynamicClient gets dynamic client inside cluster
+func GetDynamicClient() dynamic.Interface {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		logrus.Fatalf("Failed to get kubernetes config in cluster: %v", err)
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	return client
+}
+
 // buildConfigOutOfCluster builds config out of cluster
 func buildConfigOutOfCluster() (*rest.Config, error) {
 	kubeconfigPath := os.Getenv("KUBECONFIG")
@@ -53,6 +66,19 @@ func GetClientOutOfCluster() kubernetes.Interface {
 	return clientset
 }
 
+// GetDynamicClientOutOfCluster gets dynamic client out of cluster
+func GetDynamicClientOutOfCluster() dynamic.Interface {
+	config, err := buildConfigOutOfCluster()
+	if err != nil {
+		logrus.Fatalf("Failed to get kubernetes config: %v", err)
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	return client
+}
+
 func k8sConfigDir() string {
 	if runtime.GOOS == "windows" {
 		return os.Getenv("USERPROFILE")
@@ -61,34 +87,51 @@ func k8sConfigDir() string {
 	return os.Getenv("HOME")
 }
 
-// GetObjectMetaData returns metadata of a given k8s object
+// GetObjectMetaData returns metadata of a given k8s object, empty if obj has none,
+// any typed or unstructured object is supported
 func GetObjectMetaData(obj interface{}) meta_v1.ObjectMeta {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return meta_v1.ObjectMeta{}
+	}
+	if objectMeta, ok := accessor.(*meta_v1.ObjectMeta); ok {
+		return *objectMeta
+	}
+	return meta_v1.ObjectMeta{
+		Name:                       accessor.GetName(),
+		GenerateName:               accessor.GetGenerateName(),
+		Namespace:                  accessor.GetNamespace(),
+		UID:                        accessor.GetUID(),
+		ResourceVersion:            accessor.GetResourceVersion(),
+		Generation:                 accessor.GetGeneration(),
+		CreationTimestamp:          accessor.GetCreationTimestamp(),
+		DeletionTimestamp:          accessor.GetDeletionTimestamp(),
+		DeletionGracePeriodSeconds: accessor.GetDeletionGracePeriodSeconds(),
+		Labels:                     accessor.GetLabels(),
+		Annotations:                accessor.GetAnnotations(),
+		OwnerReferences:            accessor.GetOwnerReferences(),
+		Finalizers:                 accessor.GetFinalizers(),
+		ManagedFields:              accessor.GetManagedFields(),
+	}
+}
 
-	var objectMeta meta_v1.ObjectMeta
+// GetObjectKind returns the kind of a given k8s object
+// typed objects from informers have an empty TypeMeta, so fall back to the Go type name
+func GetObjectKind(obj interface{}) string {
+	if o, ok := obj.(interface {
+		GetObjectKind() schema.ObjectKind
+	}); ok {
+		if kind := o.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+			return kind
+		}
+	}
 
-	switch object := obj.(type) {
-	case *apps_v1.Deployment:
-		objectMeta = object.ObjectMeta
-	case *api_v1.ReplicationController:
-		objectMeta = object.ObjectMeta
-	case *apps_v1.ReplicaSet:
-		objectMeta = object.ObjectMeta
-	case *apps_v1.DaemonSet:
-		objectMeta = object.ObjectMeta
-	case *api_v1.Service:
-		objectMeta = object.ObjectMeta
-	case *api_v1.Pod:
-		objectMeta = object.ObjectMeta
-	case *batch_v1.Job:
-		objectMeta = object.ObjectMeta
-	case *api_v1.PersistentVolume:
-		objectMeta = object.ObjectMeta
-	case *api_v1.Namespace:
-		objectMeta = object.ObjectMeta
-	case *api_v1.Secret:
-		objectMeta = object.ObjectMeta
-	case *ext_v1beta1.Ingress:
-		objectMeta = object.ObjectMeta
-	}
-	return objectMeta
+	t := reflect.TypeOf(obj)
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
 }
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+
+	api_v1 "k8s.io/api/core/v1"
+	rbac_v1 "k8s.io/api/rbac/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestGetObjectMetaData(t *testing.T) {
+	crd := &unstructured.Unstructured{}
+	crd.SetAPIVersion("example.com/v1")
+	crd.SetKind("Widget")
+	crd.SetNamespace("default")
+	crd.SetName("widget")
+	crd.SetLabels(map[string]string{"app": "widget"})
+
+	tests := []struct {
+		name          string
+		obj           interface{}
+		wantNamespace string
+		wantName      string
+		wantLabels    int
+	}{
+		{"pod", &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "web", Labels: map[string]string{"app": "web"}}}, "default", "web", 1},
+		{"role", &rbac_v1.Role{ObjectMeta: meta_v1.ObjectMeta{Namespace: "kube-system", Name: "reader"}}, "kube-system", "reader", 0},
+		{"cluster scoped", &rbac_v1.ClusterRole{ObjectMeta: meta_v1.ObjectMeta{Name: "admin"}}, "", "admin", 0},
+		{"unstructured", crd, "default", "widget", 1},
+		{"not an object", "web", "", "", 0},
+		{"nil", nil, "", "", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := GetObjectMetaData(test.obj)
+			if got.Namespace != test.wantNamespace || got.Name != test.wantName || len(got.Labels) != test.wantLabels {
+				t.Errorf("GetObjectMetaData() = %s/%s with %d labels, want %s/%s with %d labels", got.Namespace, got.Name, len(got.Labels), test.wantNamespace, test.wantName, test.wantLabels)
+			}
+		})
+	}
+}